@@ -0,0 +1,197 @@
+package Api
+
+import (
+	"agent/Collect"
+	"agent/Metrics"
+	"agent/Middleware"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 敏感配置项的脱敏显示
+const redacted = "******"
+
+// 状态接口返回的内容
+type statusResponse struct {
+	Middleware.AgentStatus
+	LogLevel  string            `json:"logLevel"`  // 当前日志级别
+	Schedules map[string]string `json:"schedules"` // 启用的采集任务及周期
+	Config    configSummary     `json:"config"`    // 配置摘要（已脱敏）
+}
+
+// 配置摘要，密钥和令牌已脱敏
+type configSummary struct {
+	Project    string          `json:"project"`
+	MetricsURL string          `json:"metricsUrl"`
+	AutoUpdate bool            `json:"autoUpdate"`
+	Metrics    map[string]bool `json:"metrics"`
+	ApiListen  string          `json:"apiListen"`
+	ApiToken   string          `json:"apiToken"`
+	Encrypted  string          `json:"encrypted"`
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redacted
+}
+
+func summarizeConfig(config Middleware.ConfigFile) configSummary {
+	return configSummary{
+		Project:    config.Agent.Project,
+		MetricsURL: config.Agent.MetricsURL,
+		AutoUpdate: config.Agent.AutoUpdate,
+		Metrics: map[string]bool{
+			"ssl":     config.Metrics.Ssl.Enable,
+			"nginx":   config.Metrics.Nginx.Enable,
+			"harbor":  config.Metrics.Harbor.Enable,
+			"k8s":     config.Metrics.K8S.Enable,
+			"disk":    config.Metrics.Disk.Enable,
+			"diskio":  config.Metrics.DiskIO.Enable,
+			"net":     config.Metrics.Net.Enable,
+			"psi":     config.Metrics.Psi.Enable == nil || *config.Metrics.Psi.Enable,
+			"top":     config.Metrics.Top.Enable,
+			"process": config.Metrics.Process.Enable,
+			"cgroup":  config.Metrics.Cgroup.Mode == "" || config.Metrics.Cgroup.Mode == Metrics.CgroupModeContainer || config.Metrics.Cgroup.Mode == Metrics.CgroupModeAuto,
+		},
+		ApiListen: config.Api.Listen,
+		ApiToken:  redact(config.Api.Token),
+		Encrypted: redact(config.Encrypted),
+	}
+}
+
+// 解析监听地址，unix: 前缀表示 unix socket
+func listen(address string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		// 清理上次未正常退出残留的 socket 文件，路径被其他文件占用时报错，避免配置错误误删文件
+		if info, err := os.Lstat(path); err == nil {
+			if info.Mode().Type() != os.ModeSocket {
+				return nil, fmt.Errorf("监听路径 %s 已存在且不是 socket 文件", path)
+			}
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		}
+		// 创建 socket 时即只允许运行用户访问，避免创建后再 chmod 之间的窗口期被其他用户连接。
+		// umask 是进程级的，监听完成后立即恢复
+		mask := syscall.Umask(0177)
+		listener, err := net.Listen("unix", path)
+		syscall.Umask(mask)
+		return listener, err
+	}
+	return net.Listen("tcp", address)
+}
+
+// Serve 启动本地状态与调试接口，阻塞直到监听失败
+func Serve(version string, config Middleware.ConfigFile) error {
+	address := config.Api.Listen
+	if address == "" {
+		address = "127.0.0.1:9110"
+	}
+	if !strings.HasPrefix(address, "unix:") && config.Api.Token == "" {
+		return fmt.Errorf("本地接口监听 TCP 地址 %s 时必须配置 api.token", address)
+	}
+
+	listener, err := listen(address)
+	if err != nil {
+		return fmt.Errorf("本地接口监听 %s 失败: %v", address, err)
+	}
+	slog.Info("本地接口已启动", "listen", address)
+
+	server := &http.Server{
+		Handler:           newHandler(version, config),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return server.Serve(listener)
+}
+
+func newHandler(version string, config Middleware.ConfigFile) http.Handler {
+	mux := http.NewServeMux()
+
+	// 健康检查不需要令牌，便于探活
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok\n")
+	})
+
+	protected := http.NewServeMux()
+	protected.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		schedules := make(map[string]string)
+		for name, interval := range Collect.Schedules(config) {
+			schedules[name] = interval.String()
+		}
+		writeJSON(w, http.StatusOK, statusResponse{
+			AgentStatus: Middleware.SnapshotStatus(version),
+			LogLevel:    Middleware.LogLevel(),
+			Schedules:   schedules,
+			Config:      summarizeConfig(config),
+		})
+	})
+	protected.HandleFunc("GET /last/{source}", func(w http.ResponseWriter, r *http.Request) {
+		payload, ok := Middleware.LastPayload(r.PathValue("source"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "该数据来源暂无采集结果"})
+			return
+		}
+		writeJSON(w, http.StatusOK, payload)
+	})
+	protected.HandleFunc("GET /loglevel", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"level": Middleware.LogLevel()})
+	})
+	protected.HandleFunc("PUT /loglevel", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 64))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := Middleware.SetLogLevel(string(body)); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"level": Middleware.LogLevel()})
+	})
+	protected.HandleFunc("/debug/pprof/", pprof.Index)
+	protected.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	protected.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	protected.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	protected.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	mux.Handle("/", requireToken(config.Api.Token, protected))
+	return mux
+}
+
+// 校验访问令牌：Authorization: Bearer <token> 或 ?token=<token>。
+// 未配置令牌时不校验，只有监听 unix socket 时允许不配置，访问控制依赖 socket 文件的权限（0600）
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if got == "" {
+				got = r.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "令牌无效"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
@@ -4,106 +4,265 @@ import (
 	"agent/Metrics"
 	"agent/Middleware"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"strconv"
+	"sync"
 	"time"
 )
 
-// 采集数据并发送数据的封装方法
-// 差异化采集频率：心跳/硬件 15秒，Nginx 15秒，K8s 60秒，SSL 5分钟
+// 采集任务：名称即发送时的数据来源（k8s 任务同时发送 k8s 和 k8sController）
+type collector struct {
+	name     string
+	interval time.Duration
+	enabled  func(config Middleware.ConfigFile) bool
+	collect  func(version string, config Middleware.ConfigFile) error
+}
+
+// 差异化采集频率：心跳/硬件/PSI/cgroup/进程排行/进程检查/磁盘 I/O/网卡 15秒，Nginx 15秒，K8s/磁盘 60秒，SSL 5分钟
+var collectors = []collector{
+	{name: "hard", interval: 15 * time.Second, enabled: always, collect: collectHardMetrics},
+	{name: "psi", interval: 15 * time.Second, enabled: func(config Middleware.ConfigFile) bool {
+		// 旧配置文件没有 psi 配置项，未配置时默认开启
+		enable := config.Metrics.Psi.Enable
+		return enable == nil || *enable
+	}, collect: collectPSIMetrics},
+	{name: "cgroup", interval: 15 * time.Second, enabled: func(config Middleware.ConfigFile) bool {
+		// 旧配置文件没有 cgroup 配置项，未配置时同 auto
+		mode := config.Metrics.Cgroup.Mode
+		return mode == "" || mode == Metrics.CgroupModeContainer || mode == Metrics.CgroupModeAuto
+	}, collect: collectCgroupMetrics},
+	{name: "top", interval: 15 * time.Second, enabled: func(config Middleware.ConfigFile) bool {
+		return config.Metrics.Top.Enable
+	}, collect: collectTopMetrics},
+	{name: "process", interval: 15 * time.Second, enabled: func(config Middleware.ConfigFile) bool {
+		return config.Metrics.Process.Enable && len(config.Metrics.Process.Watch) > 0
+	}, collect: collectProcessMetrics},
+	{name: "heart", interval: 15 * time.Second, enabled: always, collect: collectHeartMetrics},
+	{name: "diskio", interval: 15 * time.Second, enabled: func(config Middleware.ConfigFile) bool {
+		return config.Metrics.DiskIO.Enable
+	}, collect: collectDiskIOMetrics},
+	{name: "net", interval: 15 * time.Second, enabled: func(config Middleware.ConfigFile) bool {
+		return config.Metrics.Net.Enable
+	}, collect: collectNetMetrics},
+	{name: "nginx", interval: 15 * time.Second, enabled: func(config Middleware.ConfigFile) bool {
+		return config.Metrics.Nginx.Enable
+	}, collect: collectNginxMetrics},
+	{name: "k8s", interval: time.Minute, enabled: func(config Middleware.ConfigFile) bool {
+		return config.Metrics.K8S.Enable
+	}, collect: collectK8sMetrics},
+	{name: "disk", interval: time.Minute, enabled: func(config Middleware.ConfigFile) bool {
+		return config.Metrics.Disk.Enable
+	}, collect: collectDiskMetrics},
+	{name: "ssl", interval: 5 * time.Minute, enabled: func(config Middleware.ConfigFile) bool {
+		return config.Metrics.Ssl.Enable
+	}, collect: collectSslMetrics},
+}
+
+func always(Middleware.ConfigFile) bool { return true }
+
+// 采集数据并发送数据的封装方法，每秒调用一次，按各任务的周期对齐到整点执行
 func CollectAndSendMetrics(Version string, config Middleware.ConfigFile) {
-	currentSecond := time.Now().Second()
-	currentMinute := time.Now().Minute()
+	now := time.Now().Unix()
+	for _, c := range collectors {
+		if !c.enabled(config) || now%int64(c.interval/time.Second) != 0 {
+			continue
+		}
+		runCollector(c, Version, config)
+	}
+}
 
-	// 每15秒：心跳、硬件、Nginx
-	if currentSecond%15 == 0 {
-		collectBaseMetrics(Version, config)
+// Schedules 当前配置下启用的采集任务及其周期
+func Schedules(config Middleware.ConfigFile) map[string]time.Duration {
+	schedules := make(map[string]time.Duration)
+	for _, c := range collectors {
+		if c.enabled(config) {
+			schedules[c.name] = c.interval
+		}
 	}
+	return schedules
+}
 
-	// 每60秒（整分钟）：K8s
-	if currentSecond == 0 && config.Metrics.K8S.Enable {
-		collectK8sMetrics(config)
+// Interval 采集任务的周期，未知任务返回 0
+func Interval(name string) time.Duration {
+	for _, c := range collectors {
+		if c.name == name {
+			return c.interval
+		}
 	}
+	return 0
+}
 
-	// 每5分钟：SSL证书
-	if currentSecond == 0 && currentMinute%5 == 0 && config.Metrics.Ssl.Enable {
-		collectSslMetrics(config)
+// 执行单个采集任务并记录运行结果
+func runCollector(c collector, version string, config Middleware.ConfigFile) {
+	start := time.Now()
+	err := c.collect(version, config)
+	duration := time.Since(start)
+	Middleware.RecordCollect(c.name, c.interval, start, duration, err)
+	if err != nil {
+		slog.Warn("采集失败", "collector", c.name, "duration", duration, "err", err)
+		return
 	}
+	slog.Debug("采集完成", "collector", c.name, "duration", duration)
 }
 
-// 基础数据采集：心跳、硬件、Nginx（15秒）
-func collectBaseMetrics(Version string, config Middleware.ConfigFile) {
-	// 获取主机信息
+// 硬件信息采集
+func collectHardMetrics(_ string, config Middleware.ConfigFile) error {
 	hostInfoSlice, err := Metrics.GetHostInfo()
 	if err != nil {
-		log.Printf("获取主机信息失败: %v", err)
-	} else {
-		CollectAndSendData("hard", hostInfoSlice, config)
+		return fmt.Errorf("获取主机信息失败: %w", err)
+	}
+	CollectAndSendData("hard", hostInfoSlice, config)
+	return nil
+}
+
+// 内核不支持 PSI 时只提示一次
+var psiUnsupportedOnce sync.Once
+
+// PSI 采集，与硬件信息同周期（15秒）
+func collectPSIMetrics(_ string, config Middleware.ConfigFile) error {
+	psi, err := Metrics.GetPSIInfo()
+	if errors.Is(err, Metrics.ErrPSIUnsupported) {
+		psiUnsupportedOnce.Do(func() {
+			slog.Info("内核不支持 PSI，跳过该采集", "collector", "psi")
+		})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("获取 PSI 信息失败: %w", err)
+	}
+	CollectAndSendData("psi", psi, config)
+	return nil
+}
+
+// 容器内的 cgroup 资源采集，auto 模式下不在容器中时跳过
+func collectCgroupMetrics(_ string, config Middleware.ConfigFile) error {
+	cgroup, err := Metrics.GetCgroupInfo(config.Metrics.Cgroup.Mode)
+	if errors.Is(err, Metrics.ErrNotInContainer) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("获取 cgroup 信息失败: %w", err)
+	}
+	CollectAndSendData("cgroup", cgroup, config)
+	return nil
+}
+
+// 进程排行采集
+func collectTopMetrics(_ string, config Middleware.ConfigFile) error {
+	top, err := Metrics.GetTopProcesses(config.Metrics.Top)
+	if err != nil {
+		return fmt.Errorf("获取进程信息失败: %w", err)
+	}
+	CollectAndSendData("top", top, config)
+	return nil
+}
+
+// 进程检查
+func collectProcessMetrics(_ string, config Middleware.ConfigFile) error {
+	status, err := Metrics.GetProcessStatus(config.Metrics.Process.Watch)
+	if err != nil {
+		return fmt.Errorf("检查进程失败: %w", err)
 	}
+	CollectAndSendData("process", status, config)
+	return nil
+}
 
+// 心跳采集
+func collectHeartMetrics(version string, config Middleware.ConfigFile) error {
 	// 转换版本号为浮动类型
-	versionFloat, err := strconv.ParseFloat(Version, 64)
+	versionFloat, err := strconv.ParseFloat(version, 64)
 	if err != nil {
-		log.Printf("转换版本号失败: %v", err)
-		return
+		return fmt.Errorf("转换版本号失败: %w", err)
 	}
 
-	// 获取心跳信息
 	ActiveInfo, err := Metrics.IsActive(config.Agent.Project, versionFloat)
 	if err != nil {
-		log.Printf("获取心跳数据失败: %v", err)
-	} else {
-		CollectAndSendData("heart", ActiveInfo, config)
+		return fmt.Errorf("获取心跳数据失败: %w", err)
 	}
+	CollectAndSendData("heart", ActiveInfo, config)
+	return nil
+}
 
-	// 如果 Nginx 采集被启用
-	if config.Metrics.Nginx.Enable {
-		NginxInfo, err := Metrics.GetNginxInfo()
-		if err != nil {
-			log.Printf("获取 nginx 信息失败: %v", err)
-		} else {
-			CollectAndSendData("nginx", NginxInfo, config)
-		}
+// Nginx 信息采集
+func collectNginxMetrics(_ string, config Middleware.ConfigFile) error {
+	NginxInfo, err := Metrics.GetNginxInfo()
+	if err != nil {
+		return fmt.Errorf("获取 nginx 信息失败: %w", err)
 	}
+	CollectAndSendData("nginx", NginxInfo, config)
+	return nil
 }
 
 // K8s数据采集（60秒）
-func collectK8sMetrics(config Middleware.ConfigFile) {
+func collectK8sMetrics(_ string, config Middleware.ConfigFile) error {
 	clientset, metricsClient, err := Metrics.InitializeClients(config.Metrics.K8S.ConfigPath)
 	if err != nil {
-		log.Printf("初始化 Kubernetes 客户端失败: %v", err)
-		return
+		return fmt.Errorf("初始化 Kubernetes 客户端失败: %w", err)
 	}
 
+	// pod 和控制器分别采集，一方失败（如集群未部署 metrics-server）不影响另一方发送
+	var errs []error
 	containerResources, err := Metrics.GetPodResources(clientset, metricsClient)
 	if err != nil {
-		log.Printf("获取 Kubernetes 资源信息失败: %v", err)
+		errs = append(errs, fmt.Errorf("获取 Kubernetes 资源信息失败: %w", err))
 	} else {
 		CollectAndSendData("k8s", containerResources, config)
 	}
 
 	controllerResources, err := Metrics.GetControllerResources(clientset)
 	if err != nil {
-		log.Printf("获取 Kubernetes 控制器资源信息失败: %v", err)
+		errs = append(errs, fmt.Errorf("获取 Kubernetes 控制器资源信息失败: %w", err))
 	} else {
 		CollectAndSendData("k8sController", controllerResources, config)
 	}
+	return errors.Join(errs...)
+}
+
+// 按挂载点的磁盘数据采集（60秒）
+func collectDiskMetrics(_ string, config Middleware.ConfigFile) error {
+	disks, err := Metrics.GetDiskInfo(config.Metrics.Disk)
+	if err != nil {
+		return fmt.Errorf("获取磁盘信息失败: %w", err)
+	}
+	CollectAndSendData("disk", disks, config)
+	return nil
+}
+
+// 磁盘 I/O 数据采集（15秒）
+func collectDiskIOMetrics(_ string, config Middleware.ConfigFile) error {
+	diskIO, err := Metrics.GetDiskIOInfo(config.Metrics.DiskIO)
+	if err != nil {
+		return fmt.Errorf("获取磁盘 I/O 信息失败: %w", err)
+	}
+	CollectAndSendData("diskio", diskIO, config)
+	return nil
+}
+
+// 网卡数据采集（15秒）
+func collectNetMetrics(_ string, config Middleware.ConfigFile) error {
+	interfaces, err := Metrics.GetNetInfo(config.Metrics.Net)
+	if err != nil {
+		return fmt.Errorf("获取网卡信息失败: %w", err)
+	}
+	CollectAndSendData("net", interfaces, config)
+	return nil
 }
 
 // SSL证书数据采集（5分钟）
-func collectSslMetrics(config Middleware.ConfigFile) {
+func collectSslMetrics(_ string, config Middleware.ConfigFile) error {
 	SslInfos, err := Metrics.GetSslInfo()
 	if err != nil {
-		log.Printf("获取 Ssl 信息失败: %v", err)
-		return
+		return fmt.Errorf("获取 Ssl 信息失败: %w", err)
 	}
 	var SslData []map[string]interface{}
 	err = json.Unmarshal([]byte(SslInfos), &SslData)
 	if err != nil {
-		log.Printf("解析 Ssl 信息失败: %v", err)
-		return
+		return fmt.Errorf("解析 Ssl 信息失败: %w", err)
 	}
 	CollectAndSendData("ssl", SslData, config)
+	return nil
 }
 
 // 采集数据并发送数据的封装方法（简单异步发送，失败直接丢弃）
@@ -112,8 +271,27 @@ func CollectAndSendData(source string, data interface{}, config Middleware.Confi
 	project := config.Agent.Project
 	key := []byte(config.Encrypted)
 
+	// 保存最近一次采集结果，供本地接口查看
+	Middleware.RecordPayload(project, source, data)
+
+	// 演练模式：只打印将要发送的内容
+	if dryRunOutput != nil {
+		printDryRun(project, source, data)
+		return
+	}
+
 	// 异步发送，不阻塞采集，监控数据可丢失
+	pendingSends.Add(1)
 	go func() {
-		_ = Middleware.SendData(metricsURL, project, data, key, source)
+		defer pendingSends.Done()
+		start := time.Now()
+		err := Middleware.SendData(metricsURL, project, data, key, source)
+		Middleware.RecordSend(source, err)
+		if err != nil {
+			sendFailed.Store(true)
+			slog.Warn("发送数据失败", "source", source, "err", err)
+			return
+		}
+		slog.Debug("发送数据完成", "source", source, "duration", time.Since(start))
 	}()
 }
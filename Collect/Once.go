@@ -0,0 +1,98 @@
+package Collect
+
+import (
+	"agent/Middleware"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// 演练模式的输出目标，非空时不加密发送，只打印 SendDataType JSON
+	dryRunOutput io.Writer
+	dryRunMutex  sync.Mutex
+
+	// 进行中的异步发送，单次运行模式需要等待全部完成
+	pendingSends sync.WaitGroup
+	sendFailed   atomic.Bool
+)
+
+// SetDryRun 开启演练模式：每条数据以一行 SendDataType JSON 写入 w，不加密也不发送
+func SetDryRun(w io.Writer) {
+	dryRunOutput = w
+}
+
+// 输出与发送时完全一致的 SendDataType JSON
+func printDryRun(project string, source string, data interface{}) {
+	jsonData, err := Middleware.MarshalSendData(project, data, source)
+	if err != nil {
+		slog.Error("序列化数据失败", "source", source, "err", err)
+		sendFailed.Store(true)
+		return
+	}
+
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	_, _ = fmt.Fprintf(dryRunOutput, "%s\n", jsonData)
+}
+
+// RunOnce 单次运行模式：立即执行一轮采集并等待发送完成。
+// sources 为空时执行配置中启用的全部任务，否则只执行指定任务（不受配置开关限制）
+func RunOnce(version string, config Middleware.ConfigFile, sources []string) error {
+	selected, err := selectCollectors(config, sources)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, c := range selected {
+		if err := c.collect(version, config); err != nil {
+			slog.Error("采集失败", "collector", c.name, "err", err)
+			failed = append(failed, c.name)
+		}
+	}
+	pendingSends.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("采集失败: %s", strings.Join(failed, ","))
+	}
+	if sendFailed.Load() {
+		return fmt.Errorf("部分数据发送失败")
+	}
+	return nil
+}
+
+// 按名称选择采集任务
+func selectCollectors(config Middleware.ConfigFile, sources []string) ([]collector, error) {
+	if len(sources) == 0 {
+		var selected []collector
+		for _, c := range collectors {
+			if c.enabled(config) {
+				selected = append(selected, c)
+			}
+		}
+		return selected, nil
+	}
+
+	byName := make(map[string]collector, len(collectors))
+	names := make([]string, 0, len(collectors))
+	for _, c := range collectors {
+		byName[c.name] = c
+		names = append(names, c.name)
+	}
+	sort.Strings(names)
+
+	var selected []collector
+	for _, source := range sources {
+		c, ok := byName[strings.TrimSpace(source)]
+		if !ok {
+			return nil, fmt.Errorf("未知的数据来源 %q，可选: %s", source, strings.Join(names, ","))
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
@@ -0,0 +1,105 @@
+package Daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// 默认锁文件名，位于可执行文件同目录
+const lockFileName = "agent.lock"
+
+// Lock 基于 flock 的单实例锁，守护进程生命周期内持有，进程退出时由内核自动释放
+type Lock struct {
+	file *os.File
+	path string
+}
+
+// DefaultLockPath 默认锁文件路径：可执行文件同目录下的 agent.lock
+func DefaultLockPath() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		return filepath.Join(os.TempDir(), lockFileName)
+	}
+	if realPath, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = realPath
+	}
+	return filepath.Join(filepath.Dir(exePath), lockFileName)
+}
+
+// AcquireLock 获取单实例锁并写入当前 PID，已被其他进程持有时返回错误
+func AcquireLock(path string) (*Lock, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("锁文件路径必须为绝对路径: %s", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建锁文件目录失败: %v", err)
+	}
+
+	// Go 打开的文件默认带 O_CLOEXEC，工作进程不会继承该锁
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开锁文件失败: %v", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			if pid, err := ReadLockPID(path); err == nil {
+				return nil, fmt.Errorf("已有实例在运行，PID: %d", pid)
+			}
+			return nil, fmt.Errorf("已有实例在运行")
+		}
+		return nil, fmt.Errorf("加锁失败: %v", err)
+	}
+
+	// 锁内容仅供 stop/status 查询，是否运行以 flock 为准
+	if err := file.Truncate(0); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("写入锁文件失败: %v", err)
+	}
+	if _, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("写入锁文件失败: %v", err)
+	}
+
+	return &Lock{file: file, path: path}, nil
+}
+
+// Release 释放锁；不删除锁文件，避免与正在加锁的新进程产生竞争
+func (l *Lock) Release() {
+	_ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	_ = l.file.Close()
+}
+
+// IsLocked 判断锁是否被某个进程持有
+func IsLocked(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = file.Close() }()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err != nil {
+		return errors.Is(err, syscall.EWOULDBLOCK)
+	}
+	_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	return false
+}
+
+// ReadLockPID 读取持有锁的进程 PID
+func ReadLockPID(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("锁文件内容无效: %v", err)
+	}
+	return pid, nil
+}
@@ -0,0 +1,123 @@
+package Daemon
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notify 按 sd_notify 协议向 systemd 发送状态（如 READY=1、WATCHDOG=1、STATUS=...），
+// 未在 systemd 下运行（NOTIFY_SOCKET 为空）时直接忽略
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	// 以 @ 开头的是 Linux 抽象命名空间套接字
+	if strings.HasPrefix(socket, "@") {
+		addr.Name = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval systemd 看门狗的心跳间隔（WATCHDOG_USEC 的一半），未启用时返回 0
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	// WATCHDOG_PID 指定了接收心跳的进程时，只有该进程需要发送
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// 守护模式下 systemd 认定的主进程是守护进程，只接受它的通知（NotifyAccess=main）。
+// 守护进程为工作进程提供一个中转套接字，把工作进程的就绪、状态和看门狗心跳转发给 systemd
+type notifyRelay struct {
+	conn *net.UnixConn
+	dir  string
+	path string
+}
+
+// 启动通知中转，未在 systemd 下运行时返回 nil
+func startNotifyRelay() (*notifyRelay, error) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return nil, nil
+	}
+	// 临时目录权限为 0700，其他用户无法冒充工作进程发送通知
+	dir, err := os.MkdirTemp("", "agent-notify-")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	relay := &notifyRelay{conn: conn, dir: dir, path: path}
+	go relay.forward()
+	return relay, nil
+}
+
+// 转发工作进程的通知，直到中转关闭
+func (r *notifyRelay) forward() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.conn.Read(buf)
+		if err != nil {
+			return
+		}
+		if state := relayedState(string(buf[:n])); state != "" {
+			if err := Notify(state); err != nil {
+				slog.Warn("转发 systemd 通知失败", "err", err)
+			}
+		}
+	}
+}
+
+// 工作进程退出（如更新后重启）不代表服务停止，STOPPING=1 由守护进程自己发送
+func relayedState(state string) string {
+	var lines []string
+	for _, line := range strings.Split(state, "\n") {
+		if line != "" && line != "STOPPING=1" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 工作进程的环境变量：NOTIFY_SOCKET 指向中转套接字；
+// WATCHDOG_PID 是守护进程的 PID，去掉后工作进程才会发送看门狗心跳
+func (r *notifyRelay) workerEnv(env []string) []string {
+	result := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "NOTIFY_SOCKET=") || strings.HasPrefix(kv, "WATCHDOG_PID=") {
+			continue
+		}
+		result = append(result, kv)
+	}
+	return append(result, "NOTIFY_SOCKET="+r.path)
+}
+
+// Close 停止转发并删除中转套接字
+func (r *notifyRelay) Close() {
+	_ = r.conn.Close()
+	_ = os.RemoveAll(r.dir)
+}
@@ -0,0 +1,110 @@
+package Daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 用本地 unixgram 套接字代替 systemd 接收通知
+func listenNotify(t *testing.T, name string) *net.UnixConn {
+	t.Helper()
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("监听 %q 失败: %v", name, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// 读取一条通知
+func readNotify(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("读取通知失败: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	// 抽象命名空间套接字在 Go 中以 @ 表示，与 NOTIFY_SOCKET 的写法一致
+	abstract := fmt.Sprintf("@agent-notify-test-%d", os.Getpid())
+
+	for _, tt := range []struct {
+		name   string
+		listen string
+	}{
+		{"path", path},
+		{"abstract", abstract},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := listenNotify(t, tt.listen)
+			t.Setenv("NOTIFY_SOCKET", tt.listen)
+
+			for _, state := range []string{"READY=1", "WATCHDOG=1"} {
+				if err := Notify(state); err != nil {
+					t.Fatalf("Notify(%q) 失败: %v", state, err)
+				}
+				if got := readNotify(t, conn); got != state {
+					t.Errorf("收到 %q，期望 %q", got, state)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifyWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := Notify("READY=1"); err != nil {
+		t.Errorf("未设置 NOTIFY_SOCKET 时应忽略，得到错误: %v", err)
+	}
+}
+
+func TestNotifyRelay(t *testing.T) {
+	systemd := filepath.Join(t.TempDir(), "notify.sock")
+	conn := listenNotify(t, systemd)
+	t.Setenv("NOTIFY_SOCKET", systemd)
+
+	relay, err := startNotifyRelay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer relay.Close()
+
+	env := relay.workerEnv([]string{"PATH=/usr/bin", "NOTIFY_SOCKET=" + systemd, "WATCHDOG_PID=1", "WATCHDOG_USEC=60000000"})
+	want := []string{"PATH=/usr/bin", "WATCHDOG_USEC=60000000", "NOTIFY_SOCKET=" + relay.path}
+	if fmt.Sprint(env) != fmt.Sprint(want) {
+		t.Errorf("workerEnv() = %q，期望 %q", env, want)
+	}
+
+	// 模拟工作进程：STOPPING=1 不转发，其余原样转发
+	worker, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: relay.path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = worker.Close() }()
+	for _, state := range []string{"READY=1\nSTATUS=采集已启动", "STOPPING=1", "WATCHDOG=1"} {
+		if _, err := worker.Write([]byte(state)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"READY=1\nSTATUS=采集已启动", "WATCHDOG=1"} {
+		if got := readNotify(t, conn); got != want {
+			t.Errorf("收到 %q，期望 %q", got, want)
+		}
+	}
+}
+
+func TestNotifyRelayWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if relay, err := startNotifyRelay(); relay != nil || err != nil {
+		t.Errorf("未设置 NOTIFY_SOCKET 时不应启动中转，得到 %v, %v", relay, err)
+	}
+}
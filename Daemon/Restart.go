@@ -0,0 +1,213 @@
+package Daemon
+
+import (
+	"agent/Metrics"
+	"agent/Middleware"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 工作进程退出码约定
+const (
+	ExitConfigError = 78 // 配置错误（同 sysexits.h 的 EX_CONFIG），重启也无法恢复
+	exitPanic       = 2  // Go 运行时 panic 的退出码，flag 解析失败和 os.Exit(2) 同样使用，需结合 stderr 判断
+
+	// 工作进程持续运行超过该时间视为已恢复，退避重新从初始值开始
+	stableUptime = time.Minute
+	// 保留工作进程最后输出的 stderr 行数
+	stderrTailLines = 30
+	// 崩溃上报使用的数据来源
+	crashSource = "agent_crash"
+)
+
+// 工作进程退出原因
+const (
+	reasonExit        = "exit"
+	reasonConfigError = "config_error"
+	reasonPanic       = "panic"
+	reasonRuntime     = "runtime_error"
+	reasonSignal      = "signal"
+)
+
+// Go 运行时 panic 或 fatal error 输出的堆栈中 goroutine 的开头，如 "goroutine 1 [running]:"
+var goroutineHeader = regexp.MustCompile(`^goroutine \d+ \[`)
+
+// stderr 最后的输出中是否有 Go 运行时的 panic 堆栈。堆栈较长时 panic: 所在行可能已不在保留范围内，
+// 以 goroutine 的开头行作为补充
+func hasPanicTrace(stderr []string) bool {
+	for _, line := range stderr {
+		if strings.HasPrefix(line, "panic: ") || strings.HasPrefix(line, "fatal error: ") || goroutineHeader.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析工作进程的退出原因和退出码，stderr 为工作进程最后的输出
+func classifyExit(err error, stderr []string) (string, int) {
+	if err == nil {
+		return reasonExit, 0
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return reasonRuntime, -1
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return reasonSignal, -1
+	}
+	switch code := exitErr.ExitCode(); code {
+	case ExitConfigError:
+		return reasonConfigError, code
+	case exitPanic:
+		if hasPanicTrace(stderr) {
+			return reasonPanic, code
+		}
+		return reasonRuntime, code
+	default:
+		return reasonRuntime, code
+	}
+}
+
+// 重启策略：指数退避 + 重启频率限制
+type restartPolicy struct {
+	backoffMin  time.Duration
+	backoffMax  time.Duration
+	maxRestarts int
+	window      time.Duration
+
+	failures int         // 连续异常退出次数
+	restarts []time.Time // 统计窗口内的重启时间
+}
+
+// 根据配置创建重启策略，未配置的项使用默认值
+func newRestartPolicy(config Middleware.ConfigFile) *restartPolicy {
+	cfg := config.Agent.Supervisor
+	p := &restartPolicy{
+		backoffMin:  secondsOr(cfg.BackoffMin, 5),
+		backoffMax:  secondsOr(cfg.BackoffMax, 300),
+		maxRestarts: cfg.MaxRestarts,
+		window:      secondsOr(cfg.RestartWindow, 600),
+	}
+	if p.maxRestarts <= 0 {
+		p.maxRestarts = 10
+	}
+	if p.backoffMax < p.backoffMin {
+		p.backoffMax = p.backoffMin
+	}
+	return p
+}
+
+func secondsOr(value int, def int) time.Duration {
+	if value <= 0 {
+		value = def
+	}
+	return time.Duration(value) * time.Second
+}
+
+// 记录一次退出，返回下次启动前的等待时间，以及是否判定为崩溃循环
+func (p *restartPolicy) next(reason string, uptime time.Duration, now time.Time) (time.Duration, bool) {
+	if uptime >= stableUptime {
+		p.failures = 0
+	}
+
+	var delay time.Duration
+	switch reason {
+	case reasonExit:
+		p.failures = 0
+		delay = p.backoffMin
+	case reasonConfigError:
+		// 配置错误需要人工修改，按最长等待重试
+		delay = p.backoffMax
+	default:
+		delay = p.backoffMin << p.failures
+		if delay <= 0 || delay > p.backoffMax {
+			delay = p.backoffMax
+		}
+		p.failures++
+	}
+
+	// 清理统计窗口之外的重启记录
+	kept := p.restarts[:0]
+	for _, t := range p.restarts {
+		if now.Sub(t) < p.window {
+			kept = append(kept, t)
+		}
+	}
+	p.restarts = append(kept, now)
+
+	if len(p.restarts) <= p.maxRestarts {
+		return delay, false
+	}
+
+	// 超过重启频率上限：等到最早的记录移出窗口，且不少于最长退避
+	wait := p.window - now.Sub(p.restarts[0])
+	if wait < p.backoffMax {
+		wait = p.backoffMax
+	}
+	return wait, true
+}
+
+// 保留最后若干行输出的写入器，用于崩溃上报
+type tailBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	partial string
+}
+
+func (b *tailBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	text := b.partial + string(data)
+	parts := strings.Split(text, "\n")
+	b.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		b.lines = append(b.lines, line)
+	}
+	if len(b.lines) > stderrTailLines {
+		b.lines = append([]string(nil), b.lines[len(b.lines)-stderrTailLines:]...)
+	}
+	return len(data), nil
+}
+
+// 返回当前保留的输出行
+func (b *tailBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := append([]string(nil), b.lines...)
+	if b.partial != "" {
+		lines = append(lines, b.partial)
+	}
+	return lines
+}
+
+// 清空缓存，工作进程重启时调用
+func (b *tailBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines = nil
+	b.partial = ""
+}
+
+// 上报崩溃循环信息，配置不完整时只记录日志
+func reportCrash(config Middleware.ConfigFile, crash Middleware.AgentCrash) {
+	if config.Agent.MetricsURL == "" || config.Encrypted == "" {
+		slog.Warn("未配置上报地址或加密盐，跳过崩溃上报", "source", crashSource)
+		return
+	}
+	if hostName, err := Metrics.GetHostName(); err == nil {
+		crash.HostName = hostName
+	}
+	err := Middleware.SendData(config.Agent.MetricsURL+"/metrics_data", config.Agent.Project,
+		[]Middleware.AgentCrash{crash}, []byte(config.Encrypted), crashSource)
+	if err != nil {
+		slog.Error("上报崩溃信息失败", "source", crashSource, "err", err)
+	}
+}
@@ -0,0 +1,256 @@
+package Daemon
+
+import (
+	"agent/Middleware"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	serviceName = "monitor-agent"
+	unitPath    = "/etc/systemd/system/" + serviceName + ".service"
+	// 内置守护模式后台运行时的输出文件，位于可执行文件同目录
+	daemonLogName = "agent.log"
+)
+
+// ServiceOptions 服务管理子命令的公共参数
+type ServiceOptions struct {
+	Version    string // 当前二进制版本号
+	ConfigPath string // 配置文件绝对路径
+	LockPath   string // 内置守护模式的单实例锁文件
+}
+
+// 是否可以使用 systemd 管理服务
+func hasSystemd() bool {
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return false
+	}
+	_, err := exec.LookPath("systemctl")
+	return err == nil
+}
+
+// 是否已安装 systemd 服务
+func unitInstalled() bool {
+	_, err := os.Stat(unitPath)
+	return err == nil && hasSystemd()
+}
+
+// 执行 systemctl 命令，失败时错误信息带上命令输出
+func systemctl(args ...string) (string, error) {
+	output, err := exec.Command("systemctl", args...).CombinedOutput()
+	text := strings.TrimSpace(string(output))
+	if err != nil {
+		return text, fmt.Errorf("systemctl %s 失败: %v %s", strings.Join(args, " "), err, text)
+	}
+	return text, nil
+}
+
+// 生成 systemd unit 内容，由 systemd 负责重启，工作进程直接运行，
+// 通过 sd_notify 上报就绪状态，主循环停止发送看门狗心跳时由 systemd 重启
+func unitContent(exePath string, configPath string) string {
+	return fmt.Sprintf(`[Unit]
+Description=monitor-agent 监控数据采集
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+WatchdogSec=60
+ExecStart=%s -config %s
+WorkingDirectory=%s
+Restart=always
+RestartSec=5
+TimeoutStopSec=15
+
+[Install]
+WantedBy=multi-user.target
+`, exePath, configPath, filepath.Dir(exePath))
+}
+
+// Install 安装并启用 systemd 服务；没有 systemd 时使用内置守护模式，无需安装
+func Install(opts ServiceOptions) error {
+	if !hasSystemd() {
+		fmt.Println("未检测到 systemd，将使用内置守护模式，执行 start 子命令即可启动")
+		return nil
+	}
+
+	exePath, err := Middleware.ResolveExecutable()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(unitPath, []byte(unitContent(exePath, opts.ConfigPath)), 0644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", unitPath, err)
+	}
+	if _, err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if _, err := systemctl("enable", serviceName); err != nil {
+		return err
+	}
+	fmt.Printf("已安装并启用 %s，执行 start 子命令启动\n", unitPath)
+	return nil
+}
+
+// Uninstall 停止并删除 systemd 服务；内置守护模式下只停止运行中的实例
+func Uninstall(opts ServiceOptions) error {
+	if !unitInstalled() {
+		return Stop(opts)
+	}
+	if _, err := systemctl("disable", "--now", serviceName); err != nil {
+		return err
+	}
+	if err := os.Remove(unitPath); err != nil {
+		return fmt.Errorf("删除 %s 失败: %v", unitPath, err)
+	}
+	if _, err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	fmt.Printf("已卸载 %s\n", unitPath)
+	return nil
+}
+
+// Start 启动服务：已安装 systemd 服务时交给 systemd，否则在后台启动内置守护模式
+func Start(opts ServiceOptions) error {
+	if unitInstalled() {
+		if _, err := systemctl("start", serviceName); err != nil {
+			return err
+		}
+		fmt.Println("已通过 systemd 启动")
+		return nil
+	}
+
+	if IsLocked(opts.LockPath) {
+		pid, _ := ReadLockPID(opts.LockPath)
+		fmt.Printf("已在运行，PID: %d\n", pid)
+		return nil
+	}
+
+	exePath, err := Middleware.ResolveExecutable()
+	if err != nil {
+		return err
+	}
+	logPath := filepath.Join(filepath.Dir(exePath), daemonLogName)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开输出文件失败: %v", err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	cmd := exec.Command(exePath, "-d", "-lock", opts.LockPath, "-config", opts.ConfigPath)
+	cmd.Dir = filepath.Dir(exePath)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	// 脱离当前终端会话，子命令退出后守护进程继续运行
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动守护进程失败: %v", err)
+	}
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+
+	// 等待守护进程拿到单实例锁
+	for i := 0; i < 50; i++ {
+		if IsLocked(opts.LockPath) {
+			fmt.Printf("已在后台启动，PID: %d，输出写入 %s\n", pid, logPath)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("守护进程未能启动，请查看 %s", logPath)
+}
+
+// Stop 停止服务：已安装 systemd 服务时交给 systemd，否则向持有锁的守护进程发送 SIGTERM
+func Stop(opts ServiceOptions) error {
+	if unitInstalled() {
+		if _, err := systemctl("stop", serviceName); err != nil {
+			return err
+		}
+		fmt.Println("已通过 systemd 停止")
+		return nil
+	}
+
+	if !IsLocked(opts.LockPath) {
+		fmt.Println("未在运行")
+		return nil
+	}
+	pid, err := ReadLockPID(opts.LockPath)
+	if err != nil {
+		return err
+	}
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("停止进程 %d 失败: %v", pid, err)
+	}
+
+	// 守护进程最多等待工作进程 10 秒，这里多留一些余量
+	for i := 0; i < 150; i++ {
+		if !IsLocked(opts.LockPath) {
+			fmt.Printf("已停止，PID: %d\n", pid)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("进程 %d 未在 15 秒内退出", pid)
+}
+
+// Status 打印运行状态，返回服务是否在运行
+func Status(opts ServiceOptions) bool {
+	fmt.Printf("版本:         %s\n", opts.Version)
+	fmt.Printf("配置文件:     %s\n", opts.ConfigPath)
+
+	running := false
+	if unitInstalled() {
+		state, _ := systemctl("is-active", serviceName)
+		running = state == "active"
+		fmt.Printf("运行方式:     systemd (%s)\n", unitPath)
+		fmt.Printf("运行状态:     %s\n", state)
+	} else {
+		fmt.Printf("运行方式:     内置守护模式 (%s)\n", opts.LockPath)
+		if IsLocked(opts.LockPath) {
+			running = true
+			pid, _ := ReadLockPID(opts.LockPath)
+			fmt.Printf("运行状态:     运行中，守护进程 PID: %d\n", pid)
+		} else {
+			fmt.Println("运行状态:     未运行")
+		}
+	}
+
+	status, err := Middleware.ReadStatusFile()
+	if err != nil {
+		fmt.Printf("工作进程状态: 无（%v）\n", err)
+		return running
+	}
+
+	stale := ""
+	if !running || time.Since(status.UpdatedAt) > time.Minute {
+		stale = "（状态已过期，工作进程可能已停止）"
+	}
+	fmt.Printf("工作进程:     PID %d，版本 %s%s\n", status.PID, status.Version, stale)
+	fmt.Printf("启动时间:     %s（已运行 %v）\n", status.StartedAt.Format(time.DateTime), status.UpdatedAt.Sub(status.StartedAt).Round(time.Second))
+	if status.ConfigPath != opts.ConfigPath {
+		fmt.Printf("运行中配置:   %s\n", status.ConfigPath)
+	}
+
+	sources := make([]string, 0, len(status.Sources))
+	for source := range status.Sources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	fmt.Println("最近发送:")
+	for _, source := range sources {
+		s := status.Sources[source]
+		result := "成功"
+		if s.LastError != "" {
+			result = "失败: " + s.LastError
+		}
+		fmt.Printf("  %-14s %s  %s（累计 %d 次，失败 %d 次）\n", source, s.LastSend.Format(time.DateTime), result, s.Sends, s.Errors)
+	}
+	return running
+}
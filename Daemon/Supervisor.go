@@ -0,0 +1,230 @@
+package Daemon
+
+import (
+	"agent/Metrics"
+	"agent/Middleware"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// 更新后的新版本在该时间内异常退出视为启动失败，回滚到旧版本
+const updateProbation = 30 * time.Second
+
+// 应用工作进程准备好的新版本，返回新版本号和是否已替换
+func applyPendingUpdate(exePath string) (string, bool) {
+	version, ok := Middleware.TakeRestartFlag(exePath)
+	if !ok {
+		return "", false
+	}
+	if err := Middleware.ApplyUpdate(exePath); err != nil {
+		slog.Error("替换新版本失败，继续运行当前版本", "err", err)
+		return "", false
+	}
+	slog.Info("已替换为新版本", "version", version)
+	return version, true
+}
+
+// 把发给守护进程的 SIGUSR1 转发给当前的工作进程，使日志级别切换同样作用于工作进程
+func forwardLogLevelSignal(worker *atomic.Pointer[os.Process]) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	go func() {
+		for range usr1 {
+			if process := worker.Load(); process != nil {
+				_ = process.Signal(syscall.SIGUSR1)
+			}
+		}
+	}()
+}
+
+// RunForever 守护模式：监控工作进程，负责单实例锁、信号处理、重启策略、更新替换和失败回滚
+func RunForever(version string, lockPath string) {
+	slog.Info("守护模式启动", "version", version, "lock", lockPath)
+
+	// 获取单实例锁（防止多开），守护进程存活期间一直持有，工作进程不接触该文件
+	lock, err := AcquireLock(lockPath)
+	if err != nil {
+		slog.Error("获取单实例锁失败，退出", "err", err)
+		os.Exit(1)
+	}
+	defer lock.Release()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	var worker atomic.Pointer[os.Process]
+	forwardLogLevelSignal(&worker)
+
+	// 获取当前可执行文件路径
+	exePath, err := Middleware.ResolveExecutable()
+	if err != nil {
+		slog.Error("获取可执行文件路径失败", "err", err)
+		lock.Release()
+		os.Exit(1)
+	}
+
+	// 清理上次异常退出残留的标志文件
+	_ = os.Remove(Middleware.RestartFlagPath(exePath))
+
+	// 在 systemd 下以守护模式运行时，经守护进程转发工作进程的通知
+	relay, err := startNotifyRelay()
+	if err != nil {
+		slog.Warn("启动 systemd 通知中转失败，工作进程的就绪和看门狗通知将被 systemd 忽略", "err", err)
+	}
+	if relay != nil {
+		defer relay.Close()
+	}
+
+	// 配置加载失败时使用默认重启策略，也不做崩溃上报。配置文件不存在时由工作进程创建默认配置并以配置错误退出
+	config, err := Middleware.ReadConfig(Middleware.ConfigPath)
+	if err != nil {
+		slog.Warn("守护进程加载配置失败，使用默认重启策略", "err", err)
+	} else if err := Middleware.SetupLogger(config.Log); err != nil {
+		slog.Warn("日志配置无效，使用默认日志", "err", err)
+	}
+	policy := newRestartPolicy(config)
+	// 崩溃上报的主机名与工作进程一致
+	Metrics.SetHostRoot(config.Agent.HostRoot)
+
+	// 最近一次更新替换的时间和版本，用于判断新版本是否启动失败
+	var updatedAt time.Time
+	var updatedVersion string
+	// 是否处于崩溃循环中，同一轮崩溃循环只上报一次
+	crashLooping := false
+	stderrTail := &tailBuffer{}
+
+	for {
+		slog.Info("启动工作进程")
+		stderrTail.Reset()
+		cmd := exec.Command(exePath, workerArgs()...)
+		// 工作进程的输出统一写入守护进程的日志目标（stderr 或轮转的日志文件）
+		cmd.Stdout = Middleware.LogOutput()
+		cmd.Stderr = io.MultiWriter(Middleware.LogOutput(), stderrTail)
+		cmd.Env = append(os.Environ(), Middleware.SupervisedEnv+"=1")
+		if relay != nil {
+			cmd.Env = relay.workerEnv(cmd.Env)
+		}
+		if err := cmd.Start(); err != nil {
+			slog.Error("启动工作进程失败", "err", err, "retry_in", policy.backoffMax)
+			if waitOrSignal(policy.backoffMax, sigChan) {
+				return
+			}
+			continue
+		}
+
+		startedAt := time.Now()
+		slog.Info("工作进程已启动", "pid", cmd.Process.Pid)
+		worker.Store(cmd.Process)
+
+		done := make(chan error, 1)
+		go func() {
+			err := cmd.Wait()
+			worker.Store(nil)
+			done <- err
+		}()
+
+		select {
+		case <-sigChan:
+			slog.Info("收到退出信号，正在停止")
+			_ = Notify("STOPPING=1")
+			if cmd.Process != nil {
+				_ = cmd.Process.Signal(syscall.SIGTERM)
+				select {
+				case <-done:
+				case <-time.After(10 * time.Second):
+					_ = cmd.Process.Kill()
+				}
+			}
+			slog.Info("已停止")
+			return
+
+		case err := <-done:
+			if version, ok := applyPendingUpdate(exePath); ok {
+				slog.Info("检测到更新，立即重启")
+				updatedAt, updatedVersion = time.Now(), version
+				continue
+			}
+
+			// 新版本启动后很快异常退出，回滚到旧版本
+			if err != nil && !updatedAt.IsZero() && time.Since(updatedAt) < updateProbation {
+				slog.Error("新版本启动失败，回滚到旧版本", "err", err)
+				if rbErr := Middleware.RollbackUpdate(exePath); rbErr != nil {
+					slog.Error("回滚失败", "err", rbErr)
+				}
+				// 记录失败的版本，回滚后的旧版本不再自动更新到该版本
+				if rjErr := Middleware.RejectVersion(exePath, updatedVersion); rjErr != nil {
+					slog.Error("记录回滚版本失败", "err", rjErr)
+				}
+				updatedAt = time.Time{}
+				continue
+			}
+			updatedAt = time.Time{}
+
+			uptime := time.Since(startedAt)
+			reason, code := classifyExit(err, stderrTail.Lines())
+			delay, crashLoop := policy.next(reason, uptime, time.Now())
+
+			switch reason {
+			case reasonExit:
+				slog.Info("工作进程退出", "restart_in", delay)
+			case reasonConfigError:
+				slog.Error("工作进程配置错误，请检查配置文件", "exit_code", code, "restart_in", delay)
+			default:
+				slog.Error("工作进程异常退出", "reason", reason, "exit_code", code, "err", err, "uptime", uptime.Round(time.Second), "restart_in", delay)
+			}
+
+			if crashLoop && !crashLooping {
+				slog.Error("检测到崩溃循环", "window", policy.window, "max_restarts", policy.maxRestarts, "restarts", len(policy.restarts))
+				// 重新加载配置，以便使用最新的上报地址
+				if latest, err := Middleware.ReadConfig(Middleware.ConfigPath); err == nil {
+					config = latest
+				}
+				reportCrash(config, Middleware.AgentCrash{
+					Version:    version,
+					Reason:     reason,
+					ExitCode:   code,
+					Restarts:   len(policy.restarts),
+					Window:     int(policy.window / time.Second),
+					NextDelay:  int(delay / time.Second),
+					LastStderr: stderrTail.Lines(),
+				})
+			}
+			crashLooping = crashLoop
+
+			if waitOrSignal(delay, sigChan) {
+				return
+			}
+		}
+	}
+}
+
+// 等待指定时间，期间收到退出信号返回 true
+func waitOrSignal(delay time.Duration, sigChan <-chan os.Signal) bool {
+	select {
+	case <-sigChan:
+		_ = Notify("STOPPING=1")
+		slog.Info("收到退出信号，已停止")
+		return true
+	case <-time.After(delay):
+		return false
+	}
+}
+
+// 工作进程沿用守护进程的启动参数，但去掉 -d 以免递归进入守护模式
+func workerArgs() []string {
+	var args []string
+	for _, arg := range os.Args[1:] {
+		switch arg {
+		case "-d", "--d", "-d=true", "--d=true":
+			continue
+		}
+		args = append(args, arg)
+	}
+	return args
+}
@@ -0,0 +1,293 @@
+package Metrics
+
+import (
+	"agent/Middleware"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cgroup 采集模式
+const (
+	CgroupModeHost      = "host"      // 不采集
+	CgroupModeContainer = "container" // 始终采集 agent 所在 cgroup
+	CgroupModeAuto      = "auto"      // 检测到运行在容器中时采集，未配置时的默认值
+)
+
+// ErrNotInContainer auto 模式下未检测到容器环境
+var ErrNotInContainer = errors.New("未运行在容器中")
+
+// cgroup v1 中内存不限制时的上限值接近 int64 最大值（按页对齐）
+const cgroupV1Unlimited = 1 << 62
+
+// agent 所在 cgroup 的各控制器目录，读取的是 agent 自身的 cgroup，不受 host_root 影响
+type cgroupDirs struct {
+	version int
+	path    string // /proc/self/cgroup 中的路径（v1 取 memory 控制器）
+	cpu     string
+	cpuacct string
+	memory  string
+	pids    string
+}
+
+// cgroup 采样缓存，用于计算 CPU 使用量和限流比例
+type cgroupSample struct {
+	usage     uint64 // 累计 CPU 时间（微秒）
+	periods   uint64
+	throttled uint64
+	time      time.Time
+}
+
+var (
+	lastCgroupSample *cgroupSample
+	cgroupLock       sync.Mutex
+)
+
+// 检测是否运行在容器中
+func inContainer() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	for _, path := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	data, err := os.ReadFile("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	for _, marker := range []string{"kubepods", "docker", "containerd", "libpod", "lxc"} {
+		if strings.Contains(string(data), marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析 /proc/self/cgroup：v1 为 "4:memory:/path"，v2 为 "0::/path"
+func readSelfCgroup() (map[string]string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths, nil
+}
+
+// 将 cgroup 路径映射为挂载点下的目录。容器内通常只挂载了自身的 cgroup（root 即自身路径），
+// 或启用了 cgroup namespace（路径为 /），目录不存在时退回到挂载点
+func cgroupDir(m mountInfo, path string) string {
+	rel := path
+	if m.root != "/" {
+		rel = strings.TrimPrefix(path, m.root)
+	}
+	dir := filepath.Join(m.mountPoint, rel)
+	if _, err := os.Stat(dir); err != nil {
+		return m.mountPoint
+	}
+	return dir
+}
+
+// 定位 agent 所在 cgroup：存在 v1 的 cpu 或 memory 控制器挂载时按 v1 处理（包括混合模式），否则按 v2
+func findCgroup() (cgroupDirs, error) {
+	paths, err := readSelfCgroup()
+	if err != nil {
+		return cgroupDirs{}, fmt.Errorf("读取 /proc/self/cgroup 失败: %w", err)
+	}
+	mounts, err := readMountInfo("/proc/self/mountinfo")
+	if err != nil {
+		return cgroupDirs{}, fmt.Errorf("读取挂载信息失败: %w", err)
+	}
+
+	v1 := func(controller string) string {
+		for _, m := range mounts {
+			if m.fsType == "cgroup" && hasOption(m.superOptions, controller) {
+				if path, ok := paths[controller]; ok {
+					return cgroupDir(m, path)
+				}
+			}
+		}
+		return ""
+	}
+	dirs := cgroupDirs{version: 1, path: paths["memory"], cpu: v1("cpu"), cpuacct: v1("cpuacct"), memory: v1("memory"), pids: v1("pids")}
+	if dirs.cpu != "" || dirs.memory != "" {
+		return dirs, nil
+	}
+
+	path, ok := paths[""]
+	if !ok {
+		return cgroupDirs{}, fmt.Errorf("未找到 cgroup")
+	}
+	for _, m := range mounts {
+		if m.fsType == "cgroup2" {
+			dir := cgroupDir(m, path)
+			return cgroupDirs{version: 2, path: path, cpu: dir, cpuacct: dir, memory: dir, pids: dir}, nil
+		}
+	}
+	return cgroupDirs{}, fmt.Errorf("未找到 cgroup 挂载点")
+}
+
+// 读取只有一个数值的文件，"max" 表示不限制，返回 0
+func readCgroupUint(dir string, name string) (uint64, error) {
+	if dir == "" {
+		return 0, os.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// 读取 "key value" 格式的文件，如 cpu.stat、memory.events、memory.oom_control
+func readCgroupStats(dir string, name string) map[string]uint64 {
+	values := make(map[string]uint64)
+	if dir == "" {
+		return values
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return values
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			values[fields[0]], _ = strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return values
+}
+
+// 读取 CPU 配额（核数），0 表示不限制
+func readCPUQuota(dirs cgroupDirs) float64 {
+	if dirs.version == 2 {
+		data, err := os.ReadFile(filepath.Join(dirs.cpu, "cpu.max"))
+		if err != nil {
+			return 0
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0
+		}
+		quota, _ := strconv.ParseFloat(fields[0], 64)
+		period, _ := strconv.ParseFloat(fields[1], 64)
+		if period <= 0 {
+			return 0
+		}
+		return quota / period
+	}
+
+	data, err := os.ReadFile(filepath.Join(dirs.cpu, "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0
+	}
+	quota, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || quota <= 0 {
+		return 0
+	}
+	period, err := readCgroupUint(dirs.cpu, "cpu.cfs_period_us")
+	if err != nil || period == 0 {
+		return 0
+	}
+	return float64(quota) / float64(period)
+}
+
+// GetCgroupInfo 读取 agent 所在 cgroup 的 CPU 配额与限流、内存上限与使用量、OOM 次数和进程数上限。
+// auto 模式（或未配置）下未运行在容器中时返回 ErrNotInContainer
+func GetCgroupInfo(mode string) ([]Middleware.CgroupInfo, error) {
+	if mode != CgroupModeContainer && !inContainer() {
+		return nil, ErrNotInContainer
+	}
+
+	cgroupLock.Lock()
+	defer cgroupLock.Unlock()
+
+	dirs, err := findCgroup()
+	if err != nil {
+		return nil, err
+	}
+	hostName, err := GetHostName()
+	if err != nil {
+		return nil, err
+	}
+
+	info := Middleware.CgroupInfo{
+		HostName: hostName,
+		Version:  dirs.version,
+		Path:     dirs.path,
+		CPUQuota: readCPUQuota(dirs),
+	}
+
+	// CPU 使用量和限流，v1 的时间单位为纳秒，v2 为微秒
+	sample := &cgroupSample{time: time.Now()}
+	cpuStat := readCgroupStats(dirs.cpu, "cpu.stat")
+	sample.periods = cpuStat["nr_periods"]
+	sample.throttled = cpuStat["nr_throttled"]
+	if dirs.version == 2 {
+		sample.usage = cpuStat["usage_usec"]
+		info.CPUThrottledTime = cpuStat["throttled_usec"]
+	} else {
+		usage, _ := readCgroupUint(dirs.cpuacct, "cpuacct.usage")
+		sample.usage = usage / 1000
+		info.CPUThrottledTime = cpuStat["throttled_time"] / 1000
+	}
+	info.CPUPeriods = sample.periods
+	info.CPUThrottledPeriods = sample.throttled
+
+	if prev := lastCgroupSample; prev != nil && sample.time.Sub(prev.time) <= time.Minute {
+		if elapsed := sample.time.Sub(prev.time).Microseconds(); elapsed > 0 {
+			info.CPUUsage = float64(subSat(sample.usage, prev.usage)) / float64(elapsed)
+		}
+		if periods := subSat(sample.periods, prev.periods); periods > 0 {
+			info.CPUThrottledPercent = float64(subSat(sample.throttled, prev.throttled)) * 100 / float64(periods)
+		}
+	}
+	lastCgroupSample = sample
+
+	// 内存上限、使用量和 OOM
+	if dirs.version == 2 {
+		info.MemoryMax, _ = readCgroupUint(dirs.memory, "memory.max")
+		info.MemoryCurrent, _ = readCgroupUint(dirs.memory, "memory.current")
+		events := readCgroupStats(dirs.memory, "memory.events")
+		info.OOMEvents = events["oom"]
+		info.OOMKills = events["oom_kill"]
+	} else {
+		info.MemoryMax, _ = readCgroupUint(dirs.memory, "memory.limit_in_bytes")
+		if info.MemoryMax >= cgroupV1Unlimited {
+			info.MemoryMax = 0
+		}
+		info.MemoryCurrent, _ = readCgroupUint(dirs.memory, "memory.usage_in_bytes")
+		// v1 没有累计的 OOM 事件计数，under_oom 表示当前是否处于 OOM 状态，单独上报
+		oom := readCgroupStats(dirs.memory, "memory.oom_control")
+		info.UnderOOM = oom["under_oom"] == 1
+		info.OOMKills = oom["oom_kill"]
+	}
+	if info.MemoryMax > 0 {
+		info.MemoryUsedPercent = float64(info.MemoryCurrent) * 100 / float64(info.MemoryMax)
+	}
+
+	// 进程数
+	info.PidsMax, _ = readCgroupUint(dirs.pids, "pids.max")
+	info.PidsCurrent, _ = readCgroupUint(dirs.pids, "pids.current")
+
+	return []Middleware.CgroupInfo{info}, nil
+}
@@ -0,0 +1,237 @@
+package Metrics
+
+import (
+	"agent/Middleware"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 单个挂载点 statfs 的超时时间，避免失联的网络文件系统阻塞采集
+const statfsTimeout = 5 * time.Second
+
+// 未配置 include_fstypes 时默认跳过的虚拟文件系统
+var virtualFsTypes = map[string]bool{
+	"proc": true, "sysfs": true, "devtmpfs": true, "devpts": true, "tmpfs": true,
+	"cgroup": true, "cgroup2": true, "pstore": true, "bpf": true, "tracefs": true,
+	"debugfs": true, "securityfs": true, "configfs": true, "fusectl": true,
+	"mqueue": true, "hugetlbfs": true, "autofs": true, "binfmt_misc": true,
+	"rpc_pipefs": true, "nsfs": true, "overlay": true, "squashfs": true,
+	"ramfs": true, "efivarfs": true, "selinuxfs": true, "fuse.lxcfs": true,
+}
+
+// /proc/self/mountinfo 中的一条挂载记录
+type mountInfo struct {
+	device       string // 主次设备号，同一文件系统的多次挂载（bind mount）设备号相同
+	root         string // 挂载的是文件系统中的哪个目录
+	mountPoint   string
+	fsType       string
+	source       string
+	superOptions string // 文件系统级挂载选项，cgroup v1 中包含控制器名称
+	readOnly     bool
+}
+
+// 解析 mountinfo，格式见 proc(5)：
+// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+func readMountInfo(path string) ([]mountInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mounts []mountInfo
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		// 可选字段以单独的 "-" 结束
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 6 || sep < 0 || len(fields) < sep+3 {
+			continue
+		}
+		mounts = append(mounts, mountInfo{
+			device:     fields[2],
+			root:       unescapeMountField(fields[3]),
+			mountPoint: unescapeMountField(fields[4]),
+			fsType:     fields[sep+1],
+			source:     unescapeMountField(fields[sep+2]),
+		})
+		m := &mounts[len(mounts)-1]
+		if len(fields) > sep+3 {
+			m.superOptions = fields[sep+3]
+		}
+		m.readOnly = hasOption(fields[5], "ro") || hasOption(m.superOptions, "ro")
+	}
+	return mounts, nil
+}
+
+// 还原 mountinfo 中以八进制转义的空格、制表符、换行和反斜杠
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func hasOption(options string, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// 按配置的文件系统类型和挂载点（支持 path.Match 通配符）过滤
+func mountSelected(m mountInfo, config Middleware.DiskConfig) bool {
+	if len(config.IncludeFsTypes) > 0 {
+		if !containsString(config.IncludeFsTypes, m.fsType) {
+			return false
+		}
+	} else if virtualFsTypes[m.fsType] {
+		return false
+	}
+	if containsString(config.ExcludeFsTypes, m.fsType) {
+		return false
+	}
+	if len(config.IncludeMounts) > 0 && !matchAny(config.IncludeMounts, m.mountPoint) {
+		return false
+	}
+	return !matchAny(config.ExcludeMounts, m.mountPoint)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 仍阻塞在 statfs 中的挂载点。超时后调用线程会一直阻塞到系统调用返回，
+// 在此之前不再对同一挂载点发起新的调用，避免失联的挂载点每个周期泄漏一个线程
+var (
+	statfsPending = make(map[string]bool)
+	statfsLock    sync.Mutex
+)
+
+// 带超时的 statfs，上次调用仍未返回时直接报错
+func statfs(mountPoint string) (syscall.Statfs_t, error) {
+	statfsLock.Lock()
+	if statfsPending[mountPoint] {
+		statfsLock.Unlock()
+		return syscall.Statfs_t{}, fmt.Errorf("上次 statfs 仍未返回，跳过")
+	}
+	statfsPending[mountPoint] = true
+	statfsLock.Unlock()
+
+	type result struct {
+		stat syscall.Statfs_t
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var stat syscall.Statfs_t
+		err := syscall.Statfs(mountPoint, &stat)
+		statfsLock.Lock()
+		delete(statfsPending, mountPoint)
+		statfsLock.Unlock()
+		done <- result{stat, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.stat, r.err
+	case <-time.After(statfsTimeout):
+		return syscall.Statfs_t{}, fmt.Errorf("statfs 超时（%s）", statfsTimeout)
+	}
+}
+
+// GetDiskInfo 获取每个真实文件系统挂载点的容量、inode 和只读状态
+func GetDiskInfo(config Middleware.DiskConfig) ([]Middleware.DiskMount, error) {
+	mounts, err := readMountInfo(hostProcSelf("mountinfo"))
+	if err != nil {
+		return nil, fmt.Errorf("读取挂载信息失败: %w", err)
+	}
+	hostName, err := GetHostName()
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []Middleware.DiskMount
+	seen := make(map[string]bool)
+	for _, m := range mounts {
+		// 同一设备只上报第一次出现的挂载点
+		if seen[m.device] || !mountSelected(m, config) {
+			continue
+		}
+
+		stat, err := statfs(hostPath(m.mountPoint))
+		if err != nil {
+			slog.Warn("获取挂载点容量失败", "collector", "disk", "mountpoint", m.mountPoint, "err", err)
+			continue
+		}
+		if stat.Blocks == 0 {
+			continue
+		}
+		seen[m.device] = true
+
+		bsize := uint64(stat.Bsize)
+		total := stat.Blocks * bsize
+		used := (stat.Blocks - stat.Bfree) * bsize
+		free := stat.Bavail * bsize
+		disk := Middleware.DiskMount{
+			HostName:    hostName,
+			MountPoint:  m.mountPoint,
+			Device:      m.source,
+			FsType:      m.fsType,
+			Total:       total,
+			Used:        used,
+			Free:        free,
+			InodesTotal: stat.Files,
+			InodesUsed:  stat.Files - stat.Ffree,
+			InodesFree:  stat.Ffree,
+			ReadOnly:    m.readOnly,
+		}
+		disk.UsedPercent = float64(used) * 100 / float64(total)
+		if used+free > 0 {
+			disk.UsedPercentOfAvail = float64(used) * 100 / float64(used+free)
+		}
+		if stat.Files > 0 {
+			disk.InodesUsedPercent = float64(disk.InodesUsed) * 100 / float64(stat.Files)
+		}
+		disks = append(disks, disk)
+	}
+	return disks, nil
+}
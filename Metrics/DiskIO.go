@@ -0,0 +1,192 @@
+package Metrics
+
+import (
+	"agent/Middleware"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// /proc/diskstats 的扇区大小固定为 512 字节，与设备实际扇区大小无关
+const diskSectorSize = 512
+
+// 始终跳过的设备，exclude_devices 在此基础上追加
+var defaultExcludeDevices = []string{"loop*", "ram*"}
+
+// 单个设备的累计计数，字段含义见内核文档 Documentation/admin-guide/iostats.rst
+type diskStat struct {
+	reads        uint64 // 完成的读请求数
+	readSectors  uint64 // 读扇区数
+	readTicks    uint64 // 读请求累计耗时（毫秒）
+	writes       uint64 // 完成的写请求数
+	writeSectors uint64 // 写扇区数
+	writeTicks   uint64 // 写请求累计耗时（毫秒）
+	inFlight     uint64 // 当前进行中的请求数
+	ioTicks      uint64 // 设备忙碌时间（毫秒）
+	queueTicks   uint64 // 请求加权累计耗时（毫秒）
+}
+
+// 磁盘 I/O 采样缓存
+var (
+	lastDiskStats map[string]diskStat
+	diskStatsTime time.Time
+	diskStatsLock sync.Mutex
+)
+
+// 读取 /proc/diskstats
+func readDiskStats() (map[string]diskStat, error) {
+	data, err := os.ReadFile(hostPath("/proc/diskstats"))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]diskStat)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 14 {
+			continue
+		}
+		var values [11]uint64
+		for i := range values {
+			values[i], _ = strconv.ParseUint(fields[3+i], 10, 64)
+		}
+		stats[fields[2]] = diskStat{
+			reads:        values[0],
+			readSectors:  values[2],
+			readTicks:    values[3],
+			writes:       values[4],
+			writeSectors: values[6],
+			writeTicks:   values[7],
+			inFlight:     values[8],
+			ioTicks:      values[9],
+			queueTicks:   values[10],
+		}
+	}
+	return stats, nil
+}
+
+// 读取系统运行时间，用于首次采样时计算开机以来的平均值
+func readUptime() (time.Duration, error) {
+	data, err := os.ReadFile(hostPath("/proc/uptime"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("无法读取系统运行时间")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// 按配置过滤设备：默认跳过 loop、ram 设备和分区
+func deviceSelected(name string, config Middleware.DiskIOConfig) bool {
+	if len(config.IncludeDevices) > 0 && !matchAny(config.IncludeDevices, name) {
+		return false
+	}
+	if matchAny(defaultExcludeDevices, name) || matchAny(config.ExcludeDevices, name) {
+		return false
+	}
+	if !config.Partitions {
+		// 整块磁盘（包括 dm、md 等虚拟块设备）在 /sys/block 下有对应目录，分区没有
+		if _, err := os.Stat(hostPath("/sys/block/" + strings.ReplaceAll(name, "/", "!"))); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// GetDiskIOInfo 根据 /proc/diskstats 两次采样的差值计算各设备的吞吐量、IOPS、平均等待、队列深度和利用率。
+// 首次采样（或距上次采样超过1分钟）返回开机以来的平均值
+func GetDiskIOInfo(config Middleware.DiskIOConfig) ([]Middleware.DiskIO, error) {
+	diskStatsLock.Lock()
+	defer diskStatsLock.Unlock()
+
+	stats, err := readDiskStats()
+	if err != nil {
+		return nil, fmt.Errorf("读取 /proc/diskstats 失败: %w", err)
+	}
+	hostName, err := GetHostName()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	previous := lastDiskStats
+	elapsed := now.Sub(diskStatsTime)
+	if previous == nil || elapsed > time.Minute {
+		// 没有可用的基线，以开机时刻的零值作为基线
+		previous = nil
+		if elapsed, err = readUptime(); err != nil {
+			return nil, err
+		}
+	}
+	lastDiskStats = stats
+	diskStatsTime = now
+
+	if elapsed <= 0 {
+		return nil, nil
+	}
+	seconds := elapsed.Seconds()
+	milliseconds := seconds * 1000
+
+	var result []Middleware.DiskIO
+	for name, cur := range stats {
+		if !deviceSelected(name, config) {
+			continue
+		}
+		var prev diskStat
+		if previous != nil {
+			var ok bool
+			if prev, ok = previous[name]; !ok {
+				// 新出现的设备，下次采样再计算
+				continue
+			}
+		}
+		// 计数器回绕或设备被重新添加时跳过本次
+		if cur.reads < prev.reads || cur.writes < prev.writes || cur.ioTicks < prev.ioTicks ||
+			cur.readSectors < prev.readSectors || cur.writeSectors < prev.writeSectors ||
+			cur.readTicks < prev.readTicks || cur.writeTicks < prev.writeTicks || cur.queueTicks < prev.queueTicks {
+			continue
+		}
+
+		reads := cur.reads - prev.reads
+		writes := cur.writes - prev.writes
+		readTicks := cur.readTicks - prev.readTicks
+		writeTicks := cur.writeTicks - prev.writeTicks
+
+		io := Middleware.DiskIO{
+			HostName:         hostName,
+			Device:           name,
+			ReadBytesPerSec:  float64((cur.readSectors-prev.readSectors)*diskSectorSize) / seconds,
+			WriteBytesPerSec: float64((cur.writeSectors-prev.writeSectors)*diskSectorSize) / seconds,
+			ReadIOPS:         float64(reads) / seconds,
+			WriteIOPS:        float64(writes) / seconds,
+			QueueDepth:       float64(cur.queueTicks-prev.queueTicks) / milliseconds,
+			UtilPercent:      float64(cur.ioTicks-prev.ioTicks) * 100 / milliseconds,
+			InFlight:         cur.inFlight,
+		}
+		if reads > 0 {
+			io.ReadAwait = float64(readTicks) / float64(reads)
+		}
+		if writes > 0 {
+			io.WriteAwait = float64(writeTicks) / float64(writes)
+		}
+		if reads+writes > 0 {
+			io.Await = float64(readTicks+writeTicks) / float64(reads+writes)
+		}
+		if io.UtilPercent > 100 {
+			io.UtilPercent = 100
+		}
+		result = append(result, io)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Device < result[j].Device })
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package Metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadDiskStats(t *testing.T) {
+	fakeHost(t, map[string]string{"/proc/diskstats": "diskstats"})
+
+	stats, err := readDiskStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4.18 之后的内核有 17 或 20 列，更早的内核为 14 列；不足 14 列的行被跳过
+	want := map[string]diskStat{
+		"loop0": {reads: 58, readSectors: 2130, readTicks: 21, ioTicks: 28, queueTicks: 21},
+		"sda": {
+			reads: 129410, readSectors: 7962810, readTicks: 76153,
+			writes: 521634, writeSectors: 18471738, writeTicks: 1221344,
+			ioTicks: 512920, queueTicks: 1318370,
+		},
+		"sda1": {reads: 412, readSectors: 32054, readTicks: 118, writes: 4, writeSectors: 8, writeTicks: 1, ioTicks: 104, queueTicks: 119},
+		"dm-0": {
+			reads: 148560, readSectors: 7817274, readTicks: 98120,
+			writes: 928620, writeSectors: 18471730, writeTicks: 3121140,
+			inFlight: 2, ioTicks: 546220, queueTicks: 3219260,
+		},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("readDiskStats() =\n%+v\n期望\n%+v", stats, want)
+	}
+}
@@ -0,0 +1,48 @@
+package Metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadMountInfo(t *testing.T) {
+	fakeHost(t, map[string]string{"/proc/1/mountinfo": "mountinfo"})
+
+	mounts, err := readMountInfo(hostProcSelf("mountinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 格式不完整的行被跳过
+	want := []mountInfo{
+		{device: "253:0", root: "/", mountPoint: "/", fsType: "ext4", source: "/dev/mapper/vg-root", superOptions: "rw,errors=remount-ro"},
+		{device: "0:21", root: "/", mountPoint: "/proc", fsType: "proc", source: "proc", superOptions: "rw"},
+		{device: "0:22", root: "/", mountPoint: "/sys", fsType: "sysfs", source: "sysfs", superOptions: "rw"},
+		{device: "0:26", root: "/", mountPoint: "/sys/fs/cgroup/memory", fsType: "cgroup", source: "cgroup", superOptions: "rw,memory"},
+		{device: "8:1", root: "/", mountPoint: "/boot", fsType: "xfs", source: "/dev/sda1", superOptions: "rw,attr2,inode64"},
+		{device: "8:17", root: "/data/export", mountPoint: "/srv/My Files", fsType: "ext4", source: "/dev/sdb1", superOptions: "rw"},
+		{device: "8:17", root: "/", mountPoint: "/mnt/backup", fsType: "ext4", source: "/dev/sdb1", superOptions: "rw", readOnly: true},
+		{device: "0:45", root: "/", mountPoint: "/mnt/nfs", fsType: "nfs4", source: "10.0.0.2:/export\ttab", superOptions: "ro,vers=4.2", readOnly: true},
+	}
+	if !reflect.DeepEqual(mounts, want) {
+		t.Errorf("readMountInfo() =\n%+v\n期望\n%+v", mounts, want)
+	}
+}
+
+func TestUnescapeMountField(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+	}{
+		{"/mnt/data", "/mnt/data"},
+		{`/mnt/My\040Files`, "/mnt/My Files"},
+		{`/mnt/a\011b\012c`, "/mnt/a\tb\nc"},
+		{`/mnt/back\134slash`, `/mnt/back\slash`},
+		// 不完整或无效的转义原样保留
+		{`/mnt/end\04`, `/mnt/end\04`},
+		{`/mnt/bad\999`, `/mnt/bad\999`},
+	} {
+		if got := unescapeMountField(tt.in); got != tt.want {
+			t.Errorf("unescapeMountField(%q) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -3,8 +3,8 @@ package Metrics
 import (
 	"agent/Middleware"
 	"fmt"
+	"log/slog"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,10 +13,8 @@ import (
 
 // CPU 采样缓存
 var (
-	lastCPUTotal uint64
-	lastCPUIdle  uint64
-	cpuMutex     sync.Mutex
-	cpuLastTime  time.Time
+	lastCPUSample *cpuSample
+	cpuMutex      sync.Mutex
 
 	// 主机名缓存
 	cachedHostname     string
@@ -24,165 +22,376 @@ var (
 	hostnameLastLoaded time.Time
 )
 
-// ============================================获取基本硬件信息
-// 获取 CPU 使用率（通过读取 /proc/stat，使用差值计算真实使用率）
-func getCPUPercent() (float64, error) {
-	cpuMutex.Lock()
-	defer cpuMutex.Unlock()
+// /proc/stat 中一行 CPU 时间（单位 jiffies），guest 已计入 user，不再单独累加
+type cpuTimes struct {
+	user, nice, system, idle, iowait, irq, softirq, steal uint64
+}
+
+func (t cpuTimes) total() uint64 {
+	return t.user + t.nice + t.system + t.idle + t.iowait + t.irq + t.softirq + t.steal
+}
+
+// 一次 /proc/stat 采样
+type cpuSample struct {
+	total     cpuTimes
+	cores     map[string]cpuTimes
+	coreNames []string // 保持 /proc/stat 中的核心顺序
+	ctxt      uint64   // 上下文切换次数
+	intr      uint64   // 中断次数
+	forks     uint64   // 创建的进程数
+	time      time.Time
+}
 
-	data, err := os.ReadFile("/proc/stat")
+// CPU 统计结果
+type cpuStats struct {
+	percent     float64 // hard 的 cpu_percent，沿用原有口径
+	total       Middleware.CPUTimes
+	cores       []Middleware.CPUTimes
+	ctxtPerSec  float64
+	intrPerSec  float64
+	forksPerSec float64
+}
+
+func readCPUSample() (*cpuSample, error) {
+	data, err := os.ReadFile(hostPath("/proc/stat"))
 	if err != nil {
-		return 0, err
-	}
-
-	lines := strings.Split(string(data), "\n")
-	if len(lines) > 0 {
-		fields := strings.Fields(lines[0])
-		if len(fields) >= 5 {
-			user, _ := strconv.ParseUint(fields[1], 10, 64)
-			nice, _ := strconv.ParseUint(fields[2], 10, 64)
-			system, _ := strconv.ParseUint(fields[3], 10, 64)
-			idle, _ := strconv.ParseUint(fields[4], 10, 64)
-
-			total := user + nice + system + idle
-
-			// 如果是第一次采样或者距离上次采样超过1分钟，初始化基线
-			if lastCPUTotal == 0 || time.Since(cpuLastTime) > time.Minute {
-				lastCPUTotal = total
-				lastCPUIdle = idle
-				cpuLastTime = time.Now()
-				// 第一次采样返回简单计算值
-				return 100 * float64(total-idle) / float64(total), nil
+		return nil, err
+	}
+
+	sample := &cpuSample{cores: make(map[string]cpuTimes), time: time.Now()}
+	found := false
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(fields[0], "cpu"):
+			var values [8]uint64
+			for i := 0; i < len(values) && i+1 < len(fields); i++ {
+				values[i], _ = strconv.ParseUint(fields[i+1], 10, 64)
+			}
+			times := cpuTimes{values[0], values[1], values[2], values[3], values[4], values[5], values[6], values[7]}
+			if fields[0] == "cpu" {
+				sample.total = times
+				found = true
+			} else {
+				sample.cores[fields[0]] = times
+				sample.coreNames = append(sample.coreNames, fields[0])
 			}
+		case fields[0] == "ctxt":
+			sample.ctxt, _ = strconv.ParseUint(fields[1], 10, 64)
+		case fields[0] == "intr":
+			sample.intr, _ = strconv.ParseUint(fields[1], 10, 64)
+		case fields[0] == "processes":
+			sample.forks, _ = strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("无法读取 CPU 信息")
+	}
+	return sample, nil
+}
 
-			// 计算差值获取真实 CPU 使用率
-			totalDelta := total - lastCPUTotal
-			idleDelta := idle - lastCPUIdle
+// 计算两次采样之间各状态的时间占比，计数器变小（CPU 热插拔）时按开机以来计算
+func cpuPercent(name string, cur, prev cpuTimes) Middleware.CPUTimes {
+	if cur.user < prev.user || cur.nice < prev.nice || cur.system < prev.system || cur.idle < prev.idle ||
+		cur.iowait < prev.iowait || cur.irq < prev.irq || cur.softirq < prev.softirq || cur.steal < prev.steal {
+		prev = cpuTimes{}
+	}
+	result := Middleware.CPUTimes{CPU: name}
+	totalDelta := cur.total() - prev.total()
+	if totalDelta == 0 {
+		return result
+	}
+	percent := func(cur, prev uint64) float64 {
+		return 100 * float64(cur-prev) / float64(totalDelta)
+	}
+	result.User = percent(cur.user, prev.user)
+	result.Nice = percent(cur.nice, prev.nice)
+	result.System = percent(cur.system, prev.system)
+	result.Idle = percent(cur.idle, prev.idle)
+	result.Iowait = percent(cur.iowait, prev.iowait)
+	result.Irq = percent(cur.irq, prev.irq)
+	result.Softirq = percent(cur.softirq, prev.softirq)
+	result.Steal = percent(cur.steal, prev.steal)
+	// 使用率不含 idle 和 iowait，steal 计入使用率，避免宿主机超卖时虚拟机看起来很空闲
+	result.Usage = 100 - result.Idle - result.Iowait
+	return result
+}
 
-			// 更新基线
-			lastCPUTotal = total
-			lastCPUIdle = idle
-			cpuLastTime = time.Now()
+// 原有的 CPU 使用率口径：(user + nice + system) / (user + nice + system + idle)，
+// 不计 iowait、irq、softirq 和 steal，保持 cpu_percent 与历史数据可比
+func legacyCPUPercent(cur, prev cpuTimes) float64 {
+	busy := func(t cpuTimes) uint64 { return t.user + t.nice + t.system }
+	if busy(cur) < busy(prev) || cur.idle < prev.idle {
+		prev = cpuTimes{}
+	}
+	totalDelta := busy(cur) + cur.idle - busy(prev) - prev.idle
+	if totalDelta == 0 {
+		return 0
+	}
+	return 100 * float64(busy(cur)-busy(prev)) / float64(totalDelta)
+}
 
-			if totalDelta == 0 {
-				return 0, nil
-			}
+// 获取 CPU 各状态占比（汇总和每个核心）以及上下文切换、中断、进程创建速率（通过读取 /proc/stat，使用差值计算）
+func getCPUStats() (cpuStats, error) {
+	cpuMutex.Lock()
+	defer cpuMutex.Unlock()
 
-			return 100 * float64(totalDelta-idleDelta) / float64(totalDelta), nil
+	sample, err := readCPUSample()
+	if err != nil {
+		return cpuStats{}, err
+	}
+
+	// 如果是第一次采样或者距离上次采样超过1分钟，以开机时刻为基线计算
+	prev := lastCPUSample
+	var elapsed float64
+	if prev == nil || sample.time.Sub(prev.time) > time.Minute {
+		uptime, err := readUptime()
+		if err != nil {
+			return cpuStats{}, err
 		}
+		prev = &cpuSample{cores: map[string]cpuTimes{}}
+		elapsed = uptime.Seconds()
+	} else {
+		elapsed = sample.time.Sub(prev.time).Seconds()
+	}
+	lastCPUSample = sample
+
+	stats := cpuStats{
+		percent: legacyCPUPercent(sample.total, prev.total),
+		total:   cpuPercent("cpu", sample.total, prev.total),
 	}
-	return 0, fmt.Errorf("无法读取 CPU 信息")
+	for _, name := range sample.coreNames {
+		stats.cores = append(stats.cores, cpuPercent(name, sample.cores[name], prev.cores[name]))
+	}
+	if elapsed > 0 {
+		stats.ctxtPerSec, _ = rate(sample.ctxt, prev.ctxt, elapsed)
+		stats.intrPerSec, _ = rate(sample.intr, prev.intr, elapsed)
+		stats.forksPerSec, _ = rate(sample.forks, prev.forks, elapsed)
+	}
+	return stats, nil
 }
 
-// 获取 CPU 核心数（使用提供的命令）
-func getCPUCount() (int, error) {
-	cmd := exec.Command("sh", "-c", "cat /proc/cpuinfo | grep 'processor' | wc -l")
-	output, err := cmd.Output()
+// 静态信息缓存：来源标识不变时直接返回上次解析的结果，避免每个采集周期重复解析
+type staticFact[T any] struct {
+	mu     sync.Mutex
+	key    string
+	value  T
+	loaded bool
+}
+
+func (f *staticFact[T]) get(key string, load func() (T, error)) (T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.loaded && f.key == key {
+		return f.value, nil
+	}
+	value, err := load()
 	if err != nil {
-		return 0, err
+		return value, err
 	}
+	f.key, f.value, f.loaded = key, value, true
+	return value, nil
+}
 
-	// 将输出的字节转换为整数
-	cpuCount, err := strconv.Atoi(strings.TrimSpace(string(output)))
+// 文件的修改时间和大小，作为缓存的来源标识
+func fileKey(path string) string {
+	info, err := os.Stat(path)
 	if err != nil {
-		return 0, err
+		return ""
 	}
-	return cpuCount, nil
+	return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
 }
 
-// 获取 CPU 型号（使用提供的命令）
-func getCPUModel() (string, error) {
-	cmd := exec.Command("sh", "-c", "cat /proc/cpuinfo | grep 'model name' | uniq | cut -f2 -d: | xargs")
-	output, err := cmd.Output()
+// CPU 静态信息
+type cpuInfo struct {
+	count int
+	model string
+}
+
+var (
+	cpuInfoFact staticFact[cpuInfo]
+	kernelFact  staticFact[string]
+)
+
+// 解析 /proc/cpuinfo：processor 行数为逻辑核心数，型号取第一个 model name（部分 ARM 机器为 Processor 或 Hardware）
+func readCPUInfo() (cpuInfo, error) {
+	data, err := os.ReadFile(hostPath("/proc/cpuinfo"))
 	if err != nil {
-		return "", err
+		return cpuInfo{}, err
+	}
+
+	var info cpuInfo
+	models := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.Join(strings.Fields(value), " ")
+		switch key {
+		case "processor":
+			info.count++
+		case "model name", "Processor", "Hardware":
+			if _, ok := models[key]; !ok {
+				models[key] = value
+			}
+		}
+	}
+	for _, key := range []string{"model name", "Processor", "Hardware"} {
+		if models[key] != "" {
+			info.model = models[key]
+			break
+		}
 	}
-	return strings.TrimSpace(string(output)), nil
+	if info.count == 0 {
+		return cpuInfo{}, fmt.Errorf("无法从 /proc/cpuinfo 读取 CPU 核心数")
+	}
+	return info, nil
 }
 
-// 获取操作系统版本
-func getOSVersion() (string, error) {
-	cmd := exec.Command("sh", "-c", "cat /etc/redhat-release")
-	output, err := cmd.Output()
+// CPU 热插拔时 /sys/devices/system/cpu/online 的内容会变化
+func cpuOnlineKey() string {
+	data, err := os.ReadFile(hostPath("/sys/devices/system/cpu/online"))
 	if err != nil {
-		return "", err
+		return ""
 	}
-	return strings.TrimSpace(string(output)), nil
+	return strings.TrimSpace(string(data))
+}
+
+// 获取 CPU 核心数
+func getCPUCount() (int, error) {
+	info, err := cpuInfoFact.get(cpuOnlineKey(), readCPUInfo)
+	return info.count, err
+}
+
+// 获取 CPU 型号
+func getCPUModel() (string, error) {
+	info, err := cpuInfoFact.get(cpuOnlineKey(), readCPUInfo)
+	return info.model, err
 }
 
-// 获取内核版本
+// 获取内核版本，运行期间不会变化，只读取一次
 func getKernelVersion() (string, error) {
-	cmd := exec.Command("sh", "-c", "uname -r")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(string(output)), nil
+	return kernelFact.get("", func() (string, error) {
+		data, err := os.ReadFile(hostPath("/proc/sys/kernel/osrelease"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	})
 }
 
-// 获取磁盘信息（通过 df 命令）
+// 获取根分区磁盘信息（通过 statfs，与 df -B1 / 的结果一致）
 func getDiskInfo() (uint64, uint64, uint64, float64, error) {
-	cmd := exec.Command("df", "-B1", "/")
-	output, err := cmd.Output()
+	stat, err := statfs(hostPath("/"))
 	if err != nil {
 		return 0, 0, 0, 0, err
 	}
+	if stat.Blocks == 0 {
+		return 0, 0, 0, 0, fmt.Errorf("无法读取磁盘信息")
+	}
+
+	bsize := uint64(stat.Bsize)
+	total := stat.Blocks * bsize
+	used := (stat.Blocks - stat.Bfree) * bsize
+	free := stat.Bavail * bsize
+	usedPercent := (float64(used) / float64(total)) * 100
+	return total, used, free, usedPercent, nil
+}
+
+// 内存信息，单位与 /proc/meminfo 一致为 KB（大页数量除外）
+type memoryInfo struct {
+	total, used, free, buffered, cached, shared, available uint64
+	usedPercent                                            float64
+
+	swapTotal, swapFree, swapUsed uint64
+	swapUsedPercent               float64
 
-	lines := strings.Split(string(output), "\n")
-	if len(lines) > 1 {
-		fields := strings.Fields(lines[1])
-		if len(fields) >= 4 {
-			total, _ := strconv.ParseUint(fields[1], 10, 64)
-			used, _ := strconv.ParseUint(fields[2], 10, 64)
-			free, _ := strconv.ParseUint(fields[3], 10, 64)
-			usedPercent := (float64(used) / float64(total)) * 100
-			return total, used, free, usedPercent, nil
+	dirty, writeback                             uint64
+	slab, sReclaimable, sUnreclaim               uint64
+	hugePagesTotal, hugePagesFree, hugePagesRsvd uint64
+	hugePageSize                                 uint64
+	commitLimit, committed                       uint64
+}
+
+// 不会下溢的减法，结果小于 0 时返回 0
+func subSat(a uint64, b ...uint64) uint64 {
+	for _, v := range b {
+		if v >= a {
+			return 0
 		}
+		a -= v
 	}
-	return 0, 0, 0, 0, fmt.Errorf("无法读取磁盘信息")
+	return a
 }
 
 // 获取内存信息
-func getMemoryInfo() (uint64, uint64, uint64, uint64, uint64, uint64, uint64, float64, error) {
-	data, err := os.ReadFile("/proc/meminfo")
+func getMemoryInfo() (memoryInfo, error) {
+	data, err := os.ReadFile(hostPath("/proc/meminfo"))
 	if err != nil {
-		return 0, 0, 0, 0, 0, 0, 0, 0, err
+		return memoryInfo{}, err
 	}
 
-	lines := strings.Split(string(data), "\n")
-	var total, free, buffered, cached, shared, available uint64
-
-	for _, line := range lines {
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
 		fields := strings.Fields(line)
 		if len(fields) >= 2 {
-			value, _ := strconv.ParseUint(fields[1], 10, 64)
-			switch fields[0] {
-			case "MemTotal:":
-				total = value
-			case "MemFree:":
-				free = value
-			case "Buffers:":
-				buffered = value
-			case "Cached:":
-				cached = value
-			case "Shmem:":
-				shared = value
-			case "MemAvailable:":
-				available = value
+			if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				values[strings.TrimSuffix(fields[0], ":")] = value
 			}
 		}
 	}
+	if values["MemTotal"] == 0 {
+		return memoryInfo{}, fmt.Errorf("无法读取内存信息")
+	}
 
-	// 计算已用内存
-	used := total - free - buffered - cached
-	usedPercent := (float64(used) / float64(total)) * 100
+	mem := memoryInfo{
+		total:          values["MemTotal"],
+		free:           values["MemFree"],
+		buffered:       values["Buffers"],
+		cached:         values["Cached"],
+		shared:         values["Shmem"],
+		swapTotal:      values["SwapTotal"],
+		swapFree:       values["SwapFree"],
+		dirty:          values["Dirty"],
+		writeback:      values["Writeback"],
+		slab:           values["Slab"],
+		sReclaimable:   values["SReclaimable"],
+		sUnreclaim:     values["SUnreclaim"],
+		hugePagesTotal: values["HugePages_Total"],
+		hugePagesFree:  values["HugePages_Free"],
+		hugePagesRsvd:  values["HugePages_Rsvd"],
+		hugePageSize:   values["Hugepagesize"],
+		commitLimit:    values["CommitLimit"],
+		committed:      values["Committed_AS"],
+	}
 
-	return total, used, free, buffered, cached, shared, available, usedPercent, nil
+	// 与 free 命令一致：已用 = 总量 - 可用。3.14 之前的内核没有 MemAvailable，按空闲 + 缓冲 + 可回收缓存估算
+	if available, ok := values["MemAvailable"]; ok {
+		mem.available = available
+	} else {
+		mem.available = mem.free + mem.buffered + mem.cached + mem.sReclaimable
+	}
+	if mem.available > mem.total {
+		mem.available = mem.total
+	}
+	mem.used = subSat(mem.total, mem.available)
+	mem.usedPercent = float64(mem.used) * 100 / float64(mem.total)
+
+	mem.swapUsed = subSat(mem.swapTotal, mem.swapFree)
+	if mem.swapTotal > 0 {
+		mem.swapUsedPercent = float64(mem.swapUsed) * 100 / float64(mem.swapTotal)
+	}
+	return mem, nil
 }
 
 // 获取负载信息
 func getLoadInfo() (float64, float64, float64, error) {
-	data, err := os.ReadFile("/proc/loadavg")
+	data, err := os.ReadFile(hostPath("/proc/loadavg"))
 	if err != nil {
 		return 0, 0, 0, err
 	}
@@ -214,7 +423,7 @@ func GetHostName() (string, error) {
 		return cachedHostname, nil
 	}
 
-	data, err := os.ReadFile("/etc/hostname")
+	data, err := os.ReadFile(hostPath("/etc/hostname"))
 	if err != nil {
 		return "", err
 	}
@@ -226,82 +435,131 @@ func GetHostName() (string, error) {
 	return cachedHostname, nil
 }
 
-// 获取完整的系统信息
+// 上次失败的探测项，变化时才输出日志，避免持续失败时每个周期都刷日志
+var (
+	lastFailedProbes string
+	probeLogMutex    sync.Mutex
+)
+
+// 获取完整的系统信息。各探测项相互独立，失败的项保持零值并记录在 ProbeErrors 中，
+// 只有全部探测项都失败时才返回错误
 func GetHostInfo() ([]Middleware.FlatSystemInfo, error) {
-	cpuPercent, err := getCPUPercent()
-	if err != nil {
-		return nil, err
+	// 读取的是 /proc、/sys 中整机的数据，容器内运行时不反映容器的 CPU 和内存限额
+	hostInfo := Middleware.FlatSystemInfo{Scope: "host", InContainer: inContainer()}
+	probes := 0
+	probe := func(field string, err error) bool {
+		probes++
+		if err != nil {
+			hostInfo.ProbeErrors = append(hostInfo.ProbeErrors, Middleware.ProbeError{Field: field, Error: err.Error()})
+			return false
+		}
+		return true
 	}
 
-	diskTotal, diskUsed, diskFree, diskUsedPercent, err := getDiskInfo()
-	if err != nil {
-		return nil, err
+	if cpuStats, err := getCPUStats(); probe("cpu", err) {
+		hostInfo.CPUPercent = cpuStats.percent
+		hostInfo.CPUUsage = cpuStats.total.Usage
+		hostInfo.CPUUser = cpuStats.total.User
+		hostInfo.CPUNice = cpuStats.total.Nice
+		hostInfo.CPUSystem = cpuStats.total.System
+		hostInfo.CPUIdle = cpuStats.total.Idle
+		hostInfo.CPUIowait = cpuStats.total.Iowait
+		hostInfo.CPUIrq = cpuStats.total.Irq
+		hostInfo.CPUSoftirq = cpuStats.total.Softirq
+		hostInfo.CPUSteal = cpuStats.total.Steal
+		hostInfo.CPUCores = cpuStats.cores
+		hostInfo.ContextSwitches = cpuStats.ctxtPerSec
+		hostInfo.Interrupts = cpuStats.intrPerSec
+		hostInfo.Forks = cpuStats.forksPerSec
 	}
 
-	// 获取内存信息，包含缓存和共享内存
-	memoryTotal, memoryUsed, memoryFree, memoryBuffered, memoryCached, memoryShared, memoryAvailable, memoryUsedPercent, err := getMemoryInfo()
-	if err != nil {
-		return nil, err
+	if total, used, free, usedPercent, err := getDiskInfo(); probe("disk", err) {
+		hostInfo.DiskTotal = total
+		hostInfo.DiskUsed = used
+		hostInfo.DiskFree = free
+		hostInfo.DiskUsedPercent = usedPercent
 	}
 
-	cpu_load1, cpu_load5, cpu_load15, err := getLoadInfo()
-	if err != nil {
-		return nil, err
+	// 获取内存信息，包含缓存和共享内存
+	if mem, err := getMemoryInfo(); probe("memory", err) {
+		hostInfo.MemoryTotal = mem.total
+		hostInfo.MemoryUsed = mem.used
+		hostInfo.MemoryFree = mem.free
+		hostInfo.MemoryBuffered = mem.buffered
+		hostInfo.MemoryCached = mem.cached
+		hostInfo.MemoryShared = mem.shared
+		hostInfo.MemoryAvailable = mem.available
+		hostInfo.MemoryUsedPercent = mem.usedPercent
+		hostInfo.SwapTotal = mem.swapTotal
+		hostInfo.SwapFree = mem.swapFree
+		hostInfo.SwapUsed = mem.swapUsed
+		hostInfo.SwapUsedPercent = mem.swapUsedPercent
+		hostInfo.MemoryDirty = mem.dirty
+		hostInfo.MemoryWriteback = mem.writeback
+		hostInfo.MemorySlab = mem.slab
+		hostInfo.MemorySReclaimable = mem.sReclaimable
+		hostInfo.MemorySUnreclaim = mem.sUnreclaim
+		hostInfo.HugePagesTotal = mem.hugePagesTotal
+		hostInfo.HugePagesFree = mem.hugePagesFree
+		hostInfo.HugePagesRsvd = mem.hugePagesRsvd
+		hostInfo.HugePageSize = mem.hugePageSize
+		hostInfo.CommitLimit = mem.commitLimit
+		hostInfo.Committed = mem.committed
 	}
 
-	hostName, err := GetHostName()
-	if err != nil {
-		return nil, err
+	if load1, load5, load15, err := getLoadInfo(); probe("load", err) {
+		hostInfo.CPULoad1 = load1
+		hostInfo.CPULoad5 = load5
+		hostInfo.CPULoad15 = load15
 	}
 
-	// 获取 CPU 核心数
-	cpuCount, err := getCPUCount()
-	if err != nil {
-		return nil, err
+	var err error
+	if hostInfo.HostName, err = GetHostName(); !probe("hostname", err) {
+		hostInfo.HostName = ""
+	}
+	if hostInfo.CPUCount, err = getCPUCount(); !probe("cpu_count", err) {
+		hostInfo.CPUCount = 0
+	}
+	if hostInfo.CPUModel, err = getCPUModel(); !probe("cpu_model", err) {
+		hostInfo.CPUModel = ""
+	}
+	if osInfo, err := getOSRelease(); probe("os", err) {
+		hostInfo.OSVersion = osInfo.PrettyName
+		hostInfo.OSID = osInfo.ID
+		hostInfo.OSVersionID = osInfo.VersionID
+	}
+	if hostInfo.KernelVersion, err = getKernelVersion(); !probe("kernel", err) {
+		hostInfo.KernelVersion = ""
 	}
 
-	// 获取 CPU 型号
-	cpuModel, err := getCPUModel()
-	if err != nil {
-		return nil, err
+	logProbeErrors(hostInfo.ProbeErrors)
+	if len(hostInfo.ProbeErrors) == probes {
+		return nil, fmt.Errorf("全部探测项均失败: %s", hostInfo.ProbeErrors[0].Error)
 	}
+	// 返回切片
+	return []Middleware.FlatSystemInfo{hostInfo}, nil
+}
 
-	// 获取操作系统版本
-	osVersion, err := getOSVersion()
-	if err != nil {
-		return nil, err
+// 失败的探测项发生变化时输出日志
+func logProbeErrors(probeErrors []Middleware.ProbeError) {
+	fields := make([]string, len(probeErrors))
+	for i, e := range probeErrors {
+		fields[i] = e.Field
 	}
+	failed := strings.Join(fields, ",")
 
-	// 获取内核版本
-	kernelVersion, err := getKernelVersion()
-	if err != nil {
-		return nil, err
+	probeLogMutex.Lock()
+	defer probeLogMutex.Unlock()
+	if failed == lastFailedProbes {
+		return
 	}
+	lastFailedProbes = failed
 
-	// 将主机信息放入切片中
-	hostInfo := Middleware.FlatSystemInfo{
-		CPUPercent:        cpuPercent,
-		DiskTotal:         diskTotal,
-		DiskUsed:          diskUsed,
-		DiskFree:          diskFree,
-		DiskUsedPercent:   diskUsedPercent,
-		MemoryTotal:       memoryTotal,
-		MemoryUsed:        memoryUsed,
-		MemoryFree:        memoryFree,
-		MemoryBuffered:    memoryBuffered,
-		MemoryCached:      memoryCached,
-		MemoryShared:      memoryShared,
-		MemoryAvailable:   memoryAvailable,
-		MemoryUsedPercent: memoryUsedPercent,
-		CPULoad1:          cpu_load1,
-		CPULoad5:          cpu_load5,
-		CPULoad15:         cpu_load15,
-		HostName:          hostName,
-		CPUCount:          cpuCount,
-		CPUModel:          cpuModel,
-		OSVersion:         osVersion,
-		KernelVersion:     kernelVersion,
+	if failed == "" {
+		slog.Info("主机信息探测已全部恢复", "collector", "hard")
+		return
+	}
+	for _, e := range probeErrors {
+		slog.Warn("主机信息探测失败，该项将不上报", "collector", "hard", "field", e.Field, "err", e.Error)
 	}
-	// 返回切片
-	return []Middleware.FlatSystemInfo{hostInfo}, nil
 }
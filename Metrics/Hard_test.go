@@ -0,0 +1,64 @@
+package Metrics
+
+import "testing"
+
+func TestGetMemoryInfo(t *testing.T) {
+	for _, tt := range []struct {
+		file          string
+		available     uint64
+		used          uint64
+		swapUsed      uint64
+		hugePagesFree uint64
+	}{
+		{file: "meminfo", available: 6006630, used: 8008840 - 6006630, swapUsed: 2097148 - 1572860, hugePagesFree: 2},
+		// 3.14 之前的内核没有 MemAvailable，按空闲 + 缓冲 + 可回收缓存估算
+		{file: "meminfo-2.6", available: 100000 + 50000 + 300000 + 40000, used: 1015000 - 490000},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			fakeHost(t, map[string]string{"/proc/meminfo": tt.file})
+
+			mem, err := getMemoryInfo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mem.available != tt.available || mem.used != tt.used {
+				t.Errorf("available/used = %d/%d，期望 %d/%d", mem.available, mem.used, tt.available, tt.used)
+			}
+			if want := float64(tt.used) * 100 / float64(mem.total); mem.usedPercent != want {
+				t.Errorf("usedPercent = %v，期望 %v", mem.usedPercent, want)
+			}
+			if mem.swapUsed != tt.swapUsed {
+				t.Errorf("swapUsed = %d，期望 %d", mem.swapUsed, tt.swapUsed)
+			}
+			if mem.hugePagesFree != tt.hugePagesFree {
+				t.Errorf("hugePagesFree = %d，期望 %d", mem.hugePagesFree, tt.hugePagesFree)
+			}
+		})
+	}
+}
+
+func TestGetMemoryInfoEmpty(t *testing.T) {
+	fakeHost(t, map[string]string{"/proc/meminfo": "proc-stat-invalid"})
+	if _, err := getMemoryInfo(); err == nil {
+		t.Error("没有 MemTotal 时应返回错误")
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	prev := cpuTimes{user: 1000, nice: 0, system: 500, idle: 8000, iowait: 100, steal: 0}
+	// 本周期：user 20、system 10、idle 40、iowait 20、steal 10，共 100
+	cur := cpuTimes{user: 1020, nice: 0, system: 510, idle: 8040, iowait: 120, steal: 10}
+
+	// 原有口径不计 iowait 和 steal：30 / (30 + 40)
+	if got, want := legacyCPUPercent(cur, prev), 100*30.0/70; got != want {
+		t.Errorf("legacyCPUPercent() = %v，期望 %v", got, want)
+	}
+	usage := cpuPercent("cpu", cur, prev)
+	if usage.Usage != 40 || usage.Iowait != 20 || usage.Steal != 10 {
+		t.Errorf("cpuPercent() = %+v，期望 usage 40、iowait 20、steal 10", usage)
+	}
+	// 计数器变小时按开机以来计算
+	if got, want := legacyCPUPercent(prev, cur), 100*1500.0/9500; got != want {
+		t.Errorf("计数器回退时 legacyCPUPercent() = %v，期望 %v", got, want)
+	}
+}
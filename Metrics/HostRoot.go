@@ -0,0 +1,33 @@
+package Metrics
+
+import "path/filepath"
+
+// 宿主机根目录的挂载点，为空表示直接读取本机的 /proc、/sys、/etc
+var hostRoot string
+
+// SetHostRoot 设置宿主机根目录的挂载点（如以 DaemonSet 运行时只读挂载的 /host），
+// 之后所有基于文件的采集和主机名都从该目录读取。需要在开始采集前调用
+func SetHostRoot(root string) {
+	if root == "" || filepath.Clean(root) == "/" {
+		hostRoot = ""
+		return
+	}
+	hostRoot = filepath.Clean(root)
+}
+
+// 宿主机上的文件路径
+func hostPath(path string) string {
+	if hostRoot == "" {
+		return path
+	}
+	return filepath.Join(hostRoot, path)
+}
+
+// 宿主机的 /proc/self/<name>。指定 host_root 时 self 指向 agent 自身所在的挂载和网络命名空间，
+// 改为读取宿主机 1 号进程的视图
+func hostProcSelf(name string) string {
+	if hostRoot == "" {
+		return filepath.Join("/proc/self", name)
+	}
+	return filepath.Join(hostRoot, "/proc/1", name)
+}
@@ -0,0 +1,50 @@
+package Metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 以临时目录作为宿主机根目录，把 testdata 中的文件复制到其中的指定路径，如 {"/proc/meminfo": "meminfo"}
+func fakeHost(t *testing.T, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	for dst, src := range files {
+		data, err := os.ReadFile(filepath.Join("testdata", src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(root, dst)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetHostRoot(root)
+	t.Cleanup(func() { SetHostRoot("") })
+}
+
+func TestHostPath(t *testing.T) {
+	for _, tt := range []struct {
+		root     string
+		path     string
+		want     string
+		wantSelf string
+	}{
+		{"", "/proc/stat", "/proc/stat", "/proc/self/mountinfo"},
+		{"/", "/proc/stat", "/proc/stat", "/proc/self/mountinfo"},
+		{"/host/", "/proc/stat", "/host/proc/stat", "/host/proc/1/mountinfo"},
+	} {
+		SetHostRoot(tt.root)
+		if got := hostPath(tt.path); got != tt.want {
+			t.Errorf("root %q: hostPath(%q) = %q，期望 %q", tt.root, tt.path, got, tt.want)
+		}
+		if got := hostProcSelf("mountinfo"); got != tt.wantSelf {
+			t.Errorf("root %q: hostProcSelf = %q，期望 %q", tt.root, got, tt.wantSelf)
+		}
+	}
+	SetHostRoot("")
+}
@@ -0,0 +1,169 @@
+package Metrics
+
+import (
+	"agent/Middleware"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 始终跳过回环接口和 k8s 节点上的容器虚拟网卡，exclude_interfaces 在此基础上追加
+var defaultExcludeInterfaces = []string{"lo", "veth*", "cali*", "flannel*", "cni*"}
+
+// 单个网卡的累计计数
+type netStat struct {
+	rxBytes, rxPackets, rxErrors, rxDrops uint64
+	txBytes, txPackets, txErrors, txDrops uint64
+}
+
+// 网卡采样缓存
+var (
+	lastNetStats map[string]netStat
+	netStatsTime time.Time
+	netStatsLock sync.Mutex
+)
+
+// 读取 /proc/net/dev，前两行为表头
+func readNetStats() (map[string]netStat, error) {
+	data, err := os.ReadFile(hostProcSelf("net/dev"))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]netStat)
+	for _, line := range strings.Split(string(data), "\n") {
+		name, counters, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 16 {
+			continue
+		}
+		var values [16]uint64
+		for i := range values {
+			values[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		stats[strings.TrimSpace(name)] = netStat{
+			rxBytes: values[0], rxPackets: values[1], rxErrors: values[2], rxDrops: values[3],
+			txBytes: values[8], txPackets: values[9], txErrors: values[10], txDrops: values[11],
+		}
+	}
+	return stats, nil
+}
+
+// 读取 /sys/class/net/<接口>/<属性>，不存在或不支持时返回空字符串
+func readNetAttr(name string, attr string) string {
+	data, err := os.ReadFile(hostPath("/sys/class/net/" + name + "/" + attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// 按配置过滤网卡
+func interfaceSelected(name string, config Middleware.NetConfig) bool {
+	if len(config.IncludeInterfaces) > 0 && !matchAny(config.IncludeInterfaces, name) {
+		return false
+	}
+	return !matchAny(defaultExcludeInterfaces, name) && !matchAny(config.ExcludeInterfaces, name)
+}
+
+// 计数器差值转换为每秒速率，计数器回绕时返回 false
+func rate(cur, prev uint64, seconds float64) (float64, bool) {
+	if cur < prev {
+		return 0, false
+	}
+	return float64(cur-prev) / seconds, true
+}
+
+// GetNetInfo 根据 /proc/net/dev 两次采样的差值计算各网卡的收发速率，并读取 /sys/class/net 中的链路状态和速率。
+// 首次采样（或距上次采样超过1分钟）返回开机以来的平均值
+func GetNetInfo(config Middleware.NetConfig) ([]Middleware.NetInterface, error) {
+	netStatsLock.Lock()
+	defer netStatsLock.Unlock()
+
+	stats, err := readNetStats()
+	if err != nil {
+		return nil, fmt.Errorf("读取 /proc/net/dev 失败: %w", err)
+	}
+	hostName, err := GetHostName()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	previous := lastNetStats
+	elapsed := now.Sub(netStatsTime)
+	if previous == nil || elapsed > time.Minute {
+		previous = nil
+		if elapsed, err = readUptime(); err != nil {
+			return nil, err
+		}
+	}
+	lastNetStats = stats
+	netStatsTime = now
+
+	if elapsed <= 0 {
+		return nil, nil
+	}
+	seconds := elapsed.Seconds()
+
+	var result []Middleware.NetInterface
+	for name, cur := range stats {
+		if !interfaceSelected(name, config) {
+			continue
+		}
+		var prev netStat
+		if previous != nil {
+			var ok bool
+			if prev, ok = previous[name]; !ok {
+				// 新出现的网卡，下次采样再计算
+				continue
+			}
+		}
+
+		iface := Middleware.NetInterface{
+			HostName:  hostName,
+			Interface: name,
+			OperState: readNetAttr(name, "operstate"),
+			LinkUp:    readNetAttr(name, "carrier") == "1",
+			SpeedMbps: -1,
+		}
+		// 虚拟网卡或链路断开时 speed 不可读或为 -1
+		if speed, err := strconv.Atoi(readNetAttr(name, "speed")); err == nil && speed > 0 {
+			iface.SpeedMbps = speed
+		}
+
+		counters := []struct {
+			target    *float64
+			cur, prev uint64
+		}{
+			{&iface.RxBytesPerSec, cur.rxBytes, prev.rxBytes},
+			{&iface.RxPacketsPerSec, cur.rxPackets, prev.rxPackets},
+			{&iface.RxErrorsPerSec, cur.rxErrors, prev.rxErrors},
+			{&iface.RxDropsPerSec, cur.rxDrops, prev.rxDrops},
+			{&iface.TxBytesPerSec, cur.txBytes, prev.txBytes},
+			{&iface.TxPacketsPerSec, cur.txPackets, prev.txPackets},
+			{&iface.TxErrorsPerSec, cur.txErrors, prev.txErrors},
+			{&iface.TxDropsPerSec, cur.txDrops, prev.txDrops},
+		}
+		valid := true
+		for _, c := range counters {
+			if *c.target, valid = rate(c.cur, c.prev, seconds); !valid {
+				break
+			}
+		}
+		// 计数器回绕或网卡被重新创建时跳过本次
+		if !valid {
+			continue
+		}
+		result = append(result, iface)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Interface < result[j].Interface })
+	return result, nil
+}
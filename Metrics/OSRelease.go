@@ -0,0 +1,136 @@
+package Metrics
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 操作系统发行版信息
+type osRelease struct {
+	ID         string // 发行版标识，如 centos、ubuntu、debian、alpine
+	VersionID  string // 版本号，如 7、22.04、3.19.1
+	PrettyName string // 完整名称，如 CentOS Linux 7 (Core)；有 /etc/redhat-release 时为其内容，如 CentOS Linux release 7.9.2009 (Core)
+}
+
+// 发行版信息的来源，按顺序尝试，第一个成功的为准
+var osReleaseSources = []struct {
+	path  string
+	parse func(data string) osRelease
+}{
+	{"/etc/os-release", parseOSRelease},
+	{"/usr/lib/os-release", parseOSRelease},
+	{"/etc/lsb-release", parseLSBRelease},
+	{"/etc/redhat-release", parseRedhatRelease},
+	{"/etc/debian_version", parseDebianVersion},
+}
+
+var osReleaseFact staticFact[osRelease]
+
+// 获取操作系统发行版信息，任一来源文件变化（系统升级）时重新读取
+func getOSRelease() (osRelease, error) {
+	keys := make([]string, len(osReleaseSources))
+	for i, source := range osReleaseSources {
+		keys[i] = fileKey(hostPath(source.path))
+	}
+
+	return osReleaseFact.get(strings.Join(keys, ","), func() (osRelease, error) {
+		for _, source := range osReleaseSources {
+			data, err := os.ReadFile(hostPath(source.path))
+			if err != nil {
+				continue
+			}
+			if release := source.parse(string(data)); release.PrettyName != "" {
+				// os_version 原先取自 /etc/redhat-release，该文件存在时保留其内容（包含小版本号）
+				if redhat, err := os.ReadFile(hostPath("/etc/redhat-release")); err == nil {
+					if text := strings.TrimSpace(string(redhat)); text != "" {
+						release.PrettyName = text
+					}
+				}
+				return release, nil
+			}
+		}
+		return osRelease{}, fmt.Errorf("无法识别操作系统发行版")
+	})
+}
+
+// 解析 KEY=value 格式的文件（os-release、lsb-release），值可以带引号
+func parseKeyValues(data string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `"'`)
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values
+}
+
+// /etc/os-release，格式见 os-release(5)
+func parseOSRelease(data string) osRelease {
+	values := parseKeyValues(data)
+	release := osRelease{
+		ID:         values["ID"],
+		VersionID:  values["VERSION_ID"],
+		PrettyName: values["PRETTY_NAME"],
+	}
+	if release.PrettyName == "" {
+		release.PrettyName = strings.TrimSpace(values["NAME"] + " " + values["VERSION"])
+	}
+	if release.PrettyName == "" {
+		release.PrettyName = release.ID
+	}
+	return release
+}
+
+// /etc/lsb-release（较老的 Ubuntu）
+func parseLSBRelease(data string) osRelease {
+	values := parseKeyValues(data)
+	release := osRelease{
+		ID:         strings.ToLower(values["DISTRIB_ID"]),
+		VersionID:  values["DISTRIB_RELEASE"],
+		PrettyName: values["DISTRIB_DESCRIPTION"],
+	}
+	if release.PrettyName == "" {
+		release.PrettyName = strings.TrimSpace(values["DISTRIB_ID"] + " " + release.VersionID)
+	}
+	return release
+}
+
+var redhatVersion = regexp.MustCompile(`release\s+(\S+)`)
+
+// /etc/redhat-release（CentOS 6 等没有 os-release 的系统），如 CentOS release 6.10 (Final)
+func parseRedhatRelease(data string) osRelease {
+	release := osRelease{PrettyName: strings.TrimSpace(data)}
+	if match := redhatVersion.FindStringSubmatch(release.PrettyName); match != nil {
+		release.VersionID = match[1]
+	}
+	switch {
+	case strings.HasPrefix(release.PrettyName, "Red Hat"):
+		release.ID = "rhel"
+	case release.PrettyName != "":
+		release.ID = strings.ToLower(strings.Fields(release.PrettyName)[0])
+	}
+	return release
+}
+
+// /etc/debian_version，只有版本号，如 12.5 或 bookworm/sid
+func parseDebianVersion(data string) osRelease {
+	version := strings.TrimSpace(data)
+	if version == "" {
+		return osRelease{}
+	}
+	return osRelease{ID: "debian", VersionID: version, PrettyName: "Debian GNU/Linux " + version}
+}
@@ -0,0 +1,69 @@
+package Metrics
+
+import "testing"
+
+func TestGetOSRelease(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		files map[string]string
+		want  osRelease
+	}{
+		{
+			name:  "os-release",
+			files: map[string]string{"/etc/os-release": "os-release/centos7"},
+			want:  osRelease{ID: "centos", VersionID: "7", PrettyName: "CentOS Linux 7 (Core)"},
+		},
+		{
+			name:  "os-release with redhat-release",
+			files: map[string]string{"/etc/os-release": "os-release/centos7", "/etc/redhat-release": "os-release/centos7-redhat"},
+			want:  osRelease{ID: "centos", VersionID: "7", PrettyName: "CentOS Linux release 7.9.2009 (Core)"},
+		},
+		{
+			name:  "usr lib os-release without pretty name",
+			files: map[string]string{"/usr/lib/os-release": "os-release/alpine"},
+			want:  osRelease{ID: "alpine", VersionID: "3.19.1", PrettyName: "Alpine Linux"},
+		},
+		{
+			name:  "lsb-release",
+			files: map[string]string{"/etc/lsb-release": "os-release/ubuntu-lsb", "/etc/debian_version": "os-release/debian_version"},
+			want:  osRelease{ID: "ubuntu", VersionID: "14.04", PrettyName: "Ubuntu 14.04.6 LTS"},
+		},
+		{
+			name:  "centos redhat-release",
+			files: map[string]string{"/etc/redhat-release": "os-release/centos6-redhat"},
+			want:  osRelease{ID: "centos", VersionID: "6.10", PrettyName: "CentOS release 6.10 (Final)"},
+		},
+		{
+			name:  "rhel redhat-release",
+			files: map[string]string{"/etc/redhat-release": "os-release/rhel6-redhat"},
+			want:  osRelease{ID: "rhel", VersionID: "6.9", PrettyName: "Red Hat Enterprise Linux Server release 6.9 (Santiago)"},
+		},
+		{
+			name:  "debian_version",
+			files: map[string]string{"/etc/debian_version": "os-release/debian_version"},
+			want:  osRelease{ID: "debian", VersionID: "bookworm/sid", PrettyName: "Debian GNU/Linux bookworm/sid"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeHost(t, tt.files)
+			// 缓存以文件的修改时间和大小为标识，不同用例的文件可能相同
+			osReleaseFact = staticFact[osRelease]{}
+
+			got, err := getOSRelease()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("getOSRelease() = %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOSReleaseUnknown(t *testing.T) {
+	fakeHost(t, nil)
+	osReleaseFact = staticFact[osRelease]{}
+	if _, err := getOSRelease(); err == nil {
+		t.Error("没有任何发行版文件时应返回错误")
+	}
+}
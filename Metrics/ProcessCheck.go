@@ -0,0 +1,233 @@
+package Metrics
+
+import (
+	"agent/Middleware"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 进程检查的 CPU 采样缓存
+var processCPUTracker procCPUTracker
+
+// 编译后的进程匹配条件
+type processMatcher struct {
+	watch   Middleware.ProcessWatch
+	cmdline *regexp.Regexp
+	pid     int // pidfile 中的进程号，0 表示未配置或读取失败
+}
+
+// 检查配置并编译命令行正则
+func newProcessMatcher(watch Middleware.ProcessWatch) (processMatcher, error) {
+	m := processMatcher{watch: watch}
+	if watch.ProcessName == "" && watch.Cmdline == "" && watch.Pidfile == "" && watch.User == "" {
+		return m, fmt.Errorf("进程检查 %q 未配置任何匹配条件", watch.Name)
+	}
+	if watch.Cmdline != "" {
+		re, err := regexp.Compile(watch.Cmdline)
+		if err != nil {
+			return m, fmt.Errorf("进程检查 %q 的命令行正则无效: %v", watch.Name, err)
+		}
+		m.cmdline = re
+	}
+	if watch.Pidfile != "" {
+		// pidfile 不存在或内容无效时视为进程未运行
+		if data, err := os.ReadFile(hostPath(watch.Pidfile)); err == nil {
+			m.pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+	}
+	return m, nil
+}
+
+// 判断进程是否满足全部已配置的条件
+func (m processMatcher) match(p procStat) bool {
+	if m.watch.Pidfile != "" && p.pid != m.pid {
+		return false
+	}
+	var args []string
+	if m.watch.ProcessName != "" || m.cmdline != nil {
+		args = readArgs(p.pid)
+	}
+	// 进程名匹配 comm（最长 15 个字符）或第一个参数的文件名
+	if m.watch.ProcessName != "" {
+		names := []string{p.name}
+		if len(args) > 0 {
+			names = append(names, path.Base(args[0]))
+		}
+		if !matchAnyName(m.watch.ProcessName, names) {
+			return false
+		}
+	}
+	if m.cmdline != nil && !m.cmdline.MatchString(strings.Join(args, " ")) {
+		return false
+	}
+	// 用户可以配置用户名或 UID
+	if m.watch.User != "" {
+		uid, err := readProcUID(p.pid)
+		if err != nil || (m.watch.User != strconv.Itoa(uid) && m.watch.User != userName(uid)) {
+			return false
+		}
+	}
+	return true
+}
+
+// 任一名称匹配通配符即可
+func matchAnyName(pattern string, names []string) bool {
+	for _, name := range names {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 已提示过的配置错误，同一错误只提示一次
+var (
+	invalidWatches     = make(map[string]bool)
+	invalidWatchesLock sync.Mutex
+)
+
+func warnInvalidWatch(err error) {
+	invalidWatchesLock.Lock()
+	defer invalidWatchesLock.Unlock()
+	if !invalidWatches[err.Error()] {
+		invalidWatches[err.Error()] = true
+		slog.Warn("进程检查配置无效", "collector", "process", "err", err)
+	}
+}
+
+// 无权限读取其他用户进程的 fd 目录时只提示一次
+var fdPermissionOnce sync.Once
+
+// 统计进程打开的文件描述符数，读取失败时返回 false。
+// 读取其他用户的进程需要 root 或 CAP_SYS_PTRACE
+func countFDs(pid int) (int, bool) {
+	entries, err := os.ReadDir(hostPath(fmt.Sprintf("/proc/%d/fd", pid)))
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			fdPermissionOnce.Do(func() {
+				slog.Warn("无权限读取进程的文件描述符，open_fds 上报为 -1，需要 root 或 CAP_SYS_PTRACE", "collector", "process", "pid", pid)
+			})
+		}
+		return 0, false
+	}
+	return len(entries), true
+}
+
+// 进程在不同 PID 命名空间中通用的标识：启动时间和所在的 PID 命名空间
+type procIdentity struct {
+	startTime uint64
+	pidNS     string
+}
+
+// agent 自身及守护进程的标识。指定 host_root 时扫描的是宿主机的 PID 命名空间，
+// agent 看到的进程号与宿主机不同，不能直接比较进程号
+func agentIdentities() []procIdentity {
+	pids := []int{os.Getpid()}
+	if os.Getenv(Middleware.SupervisedEnv) == "1" {
+		pids = append(pids, os.Getppid())
+	}
+	var ids []procIdentity
+	for _, pid := range pids {
+		stat, err := readProcStatFile(fmt.Sprintf("/proc/%d/stat", pid), pid)
+		if err != nil {
+			continue
+		}
+		ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, procIdentity{startTime: stat.startTime, pidNS: ns})
+	}
+	return ids
+}
+
+// 判断扫描到的进程是否为 agent 自身或守护进程，启动时间相同时再比较 PID 命名空间
+func isAgentProcess(p procStat, ids []procIdentity) bool {
+	for _, id := range ids {
+		if p.startTime != id.startTime {
+			continue
+		}
+		if ns, err := os.Readlink(hostPath(fmt.Sprintf("/proc/%d/ns/pid", p.pid))); err == nil && ns == id.pidNS {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProcessStatus 按配置的进程名、命令行正则、pidfile 和用户检查进程是否运行，
+// 并汇总匹配进程的实例数、运行时长、常驻内存、CPU 使用率、文件描述符数和线程数
+func GetProcessStatus(watches []Middleware.ProcessWatch) ([]Middleware.ProcessStatus, error) {
+	// 配置有误的检查单独上报错误，不影响其他检查
+	matchers := make([]processMatcher, len(watches))
+	invalid := make([]error, len(watches))
+	for i, watch := range watches {
+		matchers[i], invalid[i] = newProcessMatcher(watch)
+		if invalid[i] != nil {
+			warnInvalidWatch(invalid[i])
+		}
+	}
+
+	procs, err := scanProcesses()
+	if err != nil {
+		return nil, err
+	}
+	percents, err := processCPUTracker.update(procs)
+	if err != nil {
+		return nil, err
+	}
+	uptime, err := readUptime()
+	if err != nil {
+		return nil, err
+	}
+	hostName, err := GetHostName()
+	if err != nil {
+		return nil, err
+	}
+
+	self := agentIdentities()
+	result := make([]Middleware.ProcessStatus, 0, len(matchers))
+	for i, m := range matchers {
+		status := Middleware.ProcessStatus{HostName: hostName, Name: m.watch.Name, PIDs: []int{}}
+		if invalid[i] != nil {
+			status.Error = invalid[i].Error()
+			result = append(result, status)
+			continue
+		}
+		var oldest uint64
+		for _, p := range procs {
+			// 跳过 agent 自身、守护进程和僵尸进程
+			if p.state == 'Z' || !m.match(p) || isAgentProcess(p, self) {
+				continue
+			}
+			if status.Instances == 0 || p.startTime < oldest {
+				oldest = p.startTime
+			}
+			status.Instances++
+			status.PIDs = append(status.PIDs, p.pid)
+			status.RSS += p.rss
+			status.CPUPercent += percents[p.key()]
+			// 任一进程读取失败时整体未知，避免把部分进程之和当作真实值
+			if fds, ok := countFDs(p.pid); ok && status.OpenFDs >= 0 {
+				status.OpenFDs += fds
+			} else {
+				status.OpenFDs = -1
+			}
+			status.Threads += p.threads
+		}
+		status.Up = status.Instances > 0
+		if status.Up {
+			if running := uptime.Seconds() - float64(oldest)/clockTicks; running > 0 {
+				status.Uptime = int64(running)
+			}
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package Metrics
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestScanProcesses(t *testing.T) {
+	fakeHost(t, map[string]string{
+		"/proc/4242/stat":    "proc-stat",
+		"/proc/4242/cmdline": "proc-cmdline",
+		"/proc/4243/stat":    "proc-stat-invalid",
+	})
+
+	procs, err := scanProcesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 进程名中的空格和括号原样保留，无法解析的进程被跳过
+	want := []procStat{{
+		pid:       4242,
+		ppid:      1,
+		name:      "tmux: server (1)",
+		state:     'S',
+		cpuTicks:  150 + 37,
+		threads:   3,
+		startTime: 81234,
+		rss:       1024 * uint64(os.Getpagesize()),
+	}}
+	if !reflect.DeepEqual(procs, want) {
+		t.Errorf("scanProcesses() = %+v，期望 %+v", procs, want)
+	}
+
+	if got := readArgs(4242); !reflect.DeepEqual(got, []string{"redis-server", "*:6379", "--requirepass", "hunter2"}) {
+		t.Errorf("readArgs() = %q", got)
+	}
+	// 内核线程没有命令行
+	if got := readCmdline(4243); got != "" {
+		t.Errorf("readCmdline() = %q，期望为空", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	r, err := newRedactor(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name    string
+		cmdline string
+		want    string
+	}{
+		{"url", "app --dsn mysql://root:hunter2@db:3306/app", "app --dsn mysql://root:******@db:3306/app"},
+		{"url without user", "worker redis://:hunter2@127.0.0.1:6379/0", "worker redis://:******@127.0.0.1:6379/0"},
+		{"url without password", "curl http://example.com:8080/path", "curl http://example.com:8080/path"},
+		{"key=value", "java -Dspring.datasource.password=hunter2 -jar app.jar", "java -Dspring.datasource.password=****** -jar app.jar"},
+		{"key value", "app --token hunter2 --api-key=abc", "app --token ****** --api-key=******"},
+		{"key colon", "app SECRET:hunter2", "app SECRET:******"},
+		{"pass flag", "redis-server --requirepass hunter2 --port 6379", "redis-server --requirepass ****** --port 6379"},
+		{"pass flag equals", "app --masterpass=hunter2", "app --masterpass=******"},
+		{"auth flag", "mongo --auth=hunter2 --authenticationDatabase admin", "mongo --auth=****** --authenticationDatabase admin"},
+		{"short -p", "mysql -uroot -phunter2 app", "mysql -uroot -p****** app"},
+		{"short -p with space", "ssh -p 22 host", "ssh -p 22 host"},
+		{"nothing to redact", "nginx: master process /usr/sbin/nginx", "nginx: master process /usr/sbin/nginx"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.redact(tt.cmdline); got != tt.want {
+				t.Errorf("redact(%q) = %q，期望 %q", tt.cmdline, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactCustomPatterns(t *testing.T) {
+	// 配置的规则追加在默认规则之后，默认规则仍然生效
+	r, err := newRedactor([]string{`(--db-dsn=)\S+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := r.redact("app --db-dsn=user/pass --password=hunter2")
+	want := "app --db-dsn=****** --password=******"
+	if got != want {
+		t.Errorf("got %q，期望 %q", got, want)
+	}
+
+	if _, err := newRedactor([]string{"("}); err == nil {
+		t.Error("无效的正则应返回错误")
+	}
+}
@@ -0,0 +1,142 @@
+package Metrics
+
+import (
+	"agent/Middleware"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 采集的 PSI 资源类型
+var psiResources = []string{"cpu", "memory", "io"}
+
+// ErrPSIUnsupported 内核不支持 PSI（4.20 之前，或启动参数 psi=0）
+var ErrPSIUnsupported = errors.New("内核不支持 PSI")
+
+// 单行 some/full 的数据
+type psiLine struct {
+	avg10, avg60, avg300 float64
+	total                uint64 // 累计阻塞时间（微秒）
+}
+
+// 单个资源的 some 和 full 数据
+type psiStat struct {
+	some, full psiLine
+}
+
+// PSI 采样缓存
+var (
+	lastPSIStats map[string]psiStat
+	psiStatsTime time.Time
+	psiStatsLock sync.Mutex
+)
+
+// 解析 /proc/pressure/<资源>：
+// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+// full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func readPSI(resource string) (psiStat, error) {
+	data, err := os.ReadFile(hostPath("/proc/pressure/" + resource))
+	if err != nil {
+		return psiStat{}, err
+	}
+
+	var stat psiStat
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var target *psiLine
+		switch fields[0] {
+		case "some":
+			target = &stat.some
+		case "full":
+			target = &stat.full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "avg10":
+				target.avg10, _ = strconv.ParseFloat(value, 64)
+			case "avg60":
+				target.avg60, _ = strconv.ParseFloat(value, 64)
+			case "avg300":
+				target.avg300, _ = strconv.ParseFloat(value, 64)
+			case "total":
+				target.total, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+	}
+	return stat, nil
+}
+
+// GetPSIInfo 读取 cpu、memory、io 的压力阻塞信息，阻塞时间按两次采样的差值计算。
+// 内核不支持 PSI 时返回 ErrPSIUnsupported
+func GetPSIInfo() ([]Middleware.PSI, error) {
+	psiStatsLock.Lock()
+	defer psiStatsLock.Unlock()
+
+	if _, err := os.Stat(hostPath("/proc/pressure")); err != nil {
+		return nil, ErrPSIUnsupported
+	}
+	hostName, err := GetHostName()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]psiStat, len(psiResources))
+	for _, resource := range psiResources {
+		stat, err := readPSI(resource)
+		if err != nil {
+			// 目录存在但 psi=0 时读取返回 EOPNOTSUPP
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.EOPNOTSUPP) {
+				return nil, ErrPSIUnsupported
+			}
+			return nil, fmt.Errorf("读取 /proc/pressure/%s 失败: %w", resource, err)
+		}
+		stats[resource] = stat
+	}
+
+	// 首次采样（或距上次采样超过1分钟）按开机以来计算
+	now := time.Now()
+	previous := lastPSIStats
+	elapsed := now.Sub(psiStatsTime)
+	if previous == nil || elapsed > time.Minute {
+		previous = map[string]psiStat{}
+		if elapsed, err = readUptime(); err != nil {
+			return nil, err
+		}
+	}
+	lastPSIStats = stats
+	psiStatsTime = now
+
+	result := make([]Middleware.PSI, 0, len(psiResources))
+	for _, resource := range psiResources {
+		cur, prev := stats[resource], previous[resource]
+		psi := Middleware.PSI{
+			HostName:     hostName,
+			Resource:     resource,
+			SomeAvg10:    cur.some.avg10,
+			SomeAvg60:    cur.some.avg60,
+			SomeAvg300:   cur.some.avg300,
+			SomeStall:    subSat(cur.some.total, prev.some.total),
+			FullAvg10:    cur.full.avg10,
+			FullAvg60:    cur.full.avg60,
+			FullAvg300:   cur.full.avg300,
+			FullStall:    subSat(cur.full.total, prev.full.total),
+			IntervalMsec: elapsed.Milliseconds(),
+		}
+		result = append(result, psi)
+	}
+	return result, nil
+}
@@ -6,7 +6,7 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
@@ -106,7 +106,7 @@ func GetSslInfo() (string, error) {
 	seenDomains := make(map[string]bool) // 用于去重
 
 	// 提取域名来源: Nginx 配置文件
-	nginxDirectory := "/etc/nginx/conf.d"
+	nginxDirectory := hostPath("/etc/nginx/conf.d")
 	err := filepath.Walk(nginxDirectory, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -115,7 +115,7 @@ func GetSslInfo() (string, error) {
 			re := regexp.MustCompile(`server_name\s+(.*);.*?(#.*)?$`)
 			domainInfos, err := extractDomainsFromFile(path, re, false)
 			if err != nil {
-				log.Printf("读取配置文件 %s 失败: %v", path, err)
+				slog.Warn("读取配置文件失败", "collector", "ssl", "path", path, "err", err)
 				return nil
 			}
 			// 去重
@@ -133,11 +133,11 @@ func GetSslInfo() (string, error) {
 	}
 
 	// 提取域名来源: /etc/hosts 文件
-	hostsPath := "/etc/hosts"
+	hostsPath := hostPath("/etc/hosts")
 	reHosts := regexp.MustCompile(`^\s*\d+\.\d+\.\d+\.\d+\s+([^\s#]+).*?(#.*)?$`)
 	hostDomainInfos, err := extractDomainsFromFile(hostsPath, reHosts, true)
 	if err != nil {
-		log.Printf("读取 hosts 文件失败: %v", err)
+		slog.Warn("读取 hosts 文件失败", "collector", "ssl", "path", hostsPath, "err", err)
 	} else {
 		// 去重并排除 localhost 行
 		for _, domainInfo := range hostDomainInfos {
@@ -157,7 +157,7 @@ func GetSslInfo() (string, error) {
 		reDomains := regexp.MustCompile(`^\s*([^\s#]+)\s*(#.*)?$`) // 新的正则表达式
 		domainFileInfos, err := extractDomainsFromFile(domainPath, reDomains, true)
 		if err != nil {
-			log.Printf("读取域名文件 %s 失败: %v", domainPath, err)
+			slog.Warn("读取域名文件失败", "collector", "ssl", "path", domainPath, "err", err)
 		} else {
 			// 去重
 			for _, domainInfo := range domainFileInfos {
@@ -168,9 +168,9 @@ func GetSslInfo() (string, error) {
 			}
 		}
 	} else if !os.IsNotExist(err) {
-		log.Printf("检查域名文件 %s 时出错: %v", domainPath, err)
+		slog.Warn("检查域名文件时出错", "collector", "ssl", "path", domainPath, "err", err)
 	} else {
-		log.Printf("域名文件 %s 不存在", domainPath)
+		slog.Debug("域名文件不存在", "collector", "ssl", "path", domainPath)
 	}
 
 	// 并发检查 SSL 信息
@@ -0,0 +1,101 @@
+package Metrics
+
+import (
+	"agent/Middleware"
+	"sort"
+)
+
+// 未配置 count 时每个排行上报的进程数
+const defaultTopCount = 10
+
+// 上报的命令行最大长度（字节）
+const maxCmdlineLength = 512
+
+// 进程排行的 CPU 采样缓存
+var topCPUTracker procCPUTracker
+
+// GetTopProcesses 扫描 /proc 统计进程总数、线程数和各状态进程数，并按 CPU 使用率和常驻内存各取前 N 个进程。
+// CPU 使用率按两次扫描的差值计算，首次扫描（或距上次超过1分钟）为进程启动以来的平均值
+func GetTopProcesses(config Middleware.TopConfig) ([]Middleware.TopProcesses, error) {
+	redact, err := newRedactor(config.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+	count := config.Count
+	if count <= 0 {
+		count = defaultTopCount
+	}
+
+	procs, err := scanProcesses()
+	if err != nil {
+		return nil, err
+	}
+	percents, err := topCPUTracker.update(procs)
+	if err != nil {
+		return nil, err
+	}
+	hostName, err := GetHostName()
+	if err != nil {
+		return nil, err
+	}
+	// 总内存读取失败时不计算内存占比
+	memory, _ := getMemoryInfo()
+
+	top := Middleware.TopProcesses{HostName: hostName, Processes: len(procs)}
+	for _, p := range procs {
+		top.Threads += p.threads
+		switch p.state {
+		case 'R':
+			top.Running++
+		case 'D':
+			top.Blocked++
+		case 'Z':
+			top.Zombies++
+		}
+	}
+
+	// 只为进入排行的进程读取用户和命令行，两个排行中重复的进程只读取一次
+	details := make(map[procKey]Middleware.ProcessInfo)
+	ranking := func(less func(a, b procStat) bool) []Middleware.ProcessInfo {
+		sorted := append([]procStat(nil), procs...)
+		sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+		if len(sorted) > count {
+			sorted = sorted[:count]
+		}
+		result := make([]Middleware.ProcessInfo, 0, len(sorted))
+		for _, p := range sorted {
+			info, ok := details[p.key()]
+			if !ok {
+				info = processInfo(p, percents[p.key()], memory.total, redact)
+				details[p.key()] = info
+			}
+			result = append(result, info)
+		}
+		return result
+	}
+	top.TopCPU = ranking(func(a, b procStat) bool { return percents[a.key()] > percents[b.key()] })
+	top.TopMemory = ranking(func(a, b procStat) bool { return a.rss > b.rss })
+
+	return []Middleware.TopProcesses{top}, nil
+}
+
+// 组装单个进程的上报信息，memTotal 单位为 KB
+func processInfo(p procStat, cpuPercent float64, memTotal uint64, redact redactor) Middleware.ProcessInfo {
+	info := Middleware.ProcessInfo{
+		PID:        p.pid,
+		PPID:       p.ppid,
+		Name:       p.name,
+		State:      string(p.state),
+		Cmdline:    truncateCmdline(redact.redact(readCmdline(p.pid)), maxCmdlineLength),
+		CPUPercent: cpuPercent,
+		RSS:        p.rss,
+		Threads:    p.threads,
+	}
+	if uid, err := readProcUID(p.pid); err == nil {
+		info.User = userName(uid)
+	}
+	if memTotal > 0 {
+		info.MemoryPercent = float64(p.rss) * 100 / float64(memTotal*1024)
+	}
+	return info
+}
@@ -3,9 +3,10 @@ package Middleware
 import (
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -55,57 +56,50 @@ func isVersionsNotMatching(localVersion, remoteVersion float64) bool {
 	return localVersion != remoteVersion
 }
 
-// 执行更新：下载新版本并替换
+// 执行更新：下载并校验新版本，受守护进程管理时交由守护进程替换重启，否则原地替换重启
 func executeUpdate(version float64, url string) error {
 	mu.Lock()
 	defer mu.Unlock()
 
-	downloadURL := fmt.Sprintf("%s/agent/agent", url)
-	newBinary := "./agent-new"
-	currentBinary := "./agent"
-
-	log.Printf("开始下载新版本 %.2f...", version)
-
-	// 下载新版本
-	resp, err := http.Get(downloadURL)
+	// 更新文件写在真实可执行文件旁边，与当前工作目录无关
+	exePath, err := ResolveExecutable()
 	if err != nil {
-		return fmt.Errorf("下载失败: %v", err)
+		return err
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("下载失败，HTTP状态码: %d", resp.StatusCode)
+	if err := checkWritable(filepath.Dir(exePath)); err != nil {
+		return err
 	}
 
-	// 创建新文件
-	out, err := os.OpenFile(newBinary, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
-	if err != nil {
-		return fmt.Errorf("创建文件失败: %v", err)
+	slog.Info("开始下载新版本", "version", version)
+
+	if err := stageBinary(url, exePath); err != nil {
+		return err
+	}
+	if err := verifyBinary(stagedPath(exePath), version); err != nil {
+		_ = os.Remove(stagedPath(exePath))
+		return err
 	}
 
-	_, err = io.Copy(out, resp.Body)
-	_ = out.Close()
-	if err != nil {
-		_ = os.Remove(newBinary)
-		return fmt.Errorf("写入文件失败: %v", err)
+	// 由守护进程负责替换、重启和失败回滚
+	if isSupervised() {
+		if err := writeRestartFlag(exePath, version); err != nil {
+			_ = os.Remove(stagedPath(exePath))
+			return err
+		}
+		slog.Info("新版本已就绪，等待守护进程替换重启", "version", version)
+		UpdateStaged <- struct{}{}
+		return nil
 	}
 
-	log.Println("下载完成，正在替换二进制文件...")
+	slog.Info("下载完成，正在替换二进制文件")
 
-	// 替换二进制文件
-	if err := os.Rename(newBinary, currentBinary); err != nil {
-		_ = os.Remove(newBinary)
-		return fmt.Errorf("替换文件失败: %v", err)
+	if err := ApplyUpdate(exePath); err != nil {
+		return err
 	}
 
-	log.Printf("更新完成，新版本: %.2f，正在原地重启...", version)
+	slog.Info("更新完成，正在原地重启", "version", version)
 
 	// 使用 syscall.Exec 原地替换当前进程，PID不变，容器无感知
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("获取可执行文件路径失败: %v", err)
-	}
-
 	// syscall.Exec 会用新程序替换当前进程，不会返回
 	err = syscall.Exec(exePath, os.Args, os.Environ())
 	if err != nil {
@@ -117,27 +111,39 @@ func executeUpdate(version float64, url string) error {
 
 // 启动一个线程定期检查版本号
 func CheckVersion(version string, url string) {
+	// 已提示过的回滚版本，同一版本只提示一次
+	var rejectedLogged float64
 	for {
 		remoteVersion, err := getVersionFromServer(fmt.Sprintf("%s/version", url))
 		if err != nil {
-			log.Printf("获取版本号失败: %v\n", err)
+			slog.Warn("获取版本号失败", "err", err)
 			time.Sleep(5 * time.Second) // 如果失败，稍后重试
 			continue
 		}
 		localVersion, err := strconv.ParseFloat(version, 64)
 		if err != nil {
 			// 处理转换错误
-			log.Println("转换错误:", err)
+			slog.Error("转换本地版本号失败", "version", version, "err", err)
 			return
 		}
 
 		// 比较本地版本与远程版本
-		if isVersionsNotMatching(localVersion, remoteVersion) {
-			log.Printf("发现新版本! 本地版本: %.2f, 远程版本: %.2f\n", localVersion, remoteVersion)
+		if isVersionsNotMatching(localVersion, remoteVersion) && isRejectedVersion(remoteVersion) {
+			if remoteVersion != rejectedLogged {
+				slog.Warn("远程版本启动失败后已回滚，跳过该版本的更新", "local", localVersion, "remote", remoteVersion)
+				rejectedLogged = remoteVersion
+			}
+		} else if isVersionsNotMatching(localVersion, remoteVersion) {
+			slog.Info("发现新版本", "local", localVersion, "remote", remoteVersion)
 			if err := executeUpdate(remoteVersion, url); err != nil {
-				log.Printf("更新失败: %v", err)
+				slog.Error("更新失败", "err", err)
+				time.Sleep(10 * time.Second) // 避免失败后频繁重复下载
 				continue
 			}
+			// 新版本已交给守护进程，不再继续检查
+			if isSupervised() {
+				return
+			}
 		}
 
 		// 等待 10 秒钟再检查一次
@@ -148,7 +154,8 @@ func CheckVersion(version string, url string) {
 func AutoChecks(Version string) {
 	config, err := LoadConfig()
 	if err != nil {
-		log.Fatalf("加载配置文件失败: %v", err)
+		slog.Error("加载配置文件失败", "err", err)
+		os.Exit(1)
 	}
 	// 启动单个 goroutine 执行版本检查（CheckVersion 内部已有循环）
 	go CheckVersion(Version, config.Agent.MetricsURL)
@@ -2,7 +2,7 @@ package Middleware
 
 import (
 	"gopkg.in/yaml.v3"
-	"log"
+	"log/slog"
 	"os"
 	"sync"
 	"time"
@@ -13,6 +13,9 @@ var (
 	configMutex      sync.RWMutex
 	configLastLoaded time.Time
 	configCacheTTL   = 30 * time.Second // 配置缓存有效期
+
+	// ConfigPath 配置文件路径，可通过 -config 参数指定
+	ConfigPath = "config.yaml"
 )
 
 // 加载配置函数（带缓存，避免重复读取文件）
@@ -36,11 +39,11 @@ func LoadConfig() (ConfigFile, error) {
 	}
 
 	var config ConfigFile
-	filePath := "config.yaml"
+	filePath := ConfigPath
 
 	// 检查配置文件是否存在
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		log.Println("配置文件不存在，创建默认配置文件...")
+		slog.Info("配置文件不存在，创建默认配置文件", "path", filePath)
 
 		// 创建带注释的默认配置文件内容
 		defaultConfig := `# 配置文件示例
@@ -56,6 +59,20 @@ agent:
   # 是否开启自动更新
   auto_update: true
 
+  # 宿主机根目录的挂载点。以 DaemonSet 运行时将宿主机 / 只读挂载到容器内（如 /host），
+  # 所有读取 /proc、/sys、/etc 的采集和主机名都从该目录读取，上报的是节点而不是容器的数据。
+  # 挂载时需设置 mountPropagation: HostToContainer，否则看不到宿主机后挂载的磁盘。为空表示直接读取本机
+  host_root: ""
+
+  # 守护模式（-d）下工作进程的重启策略，单位秒
+  supervisor:
+    # 异常退出后的重启等待，按次数指数增长，最长 backoff_max
+    backoff_min: 5
+    backoff_max: 300
+    # restart_window 秒内重启超过 max_restarts 次判定为崩溃循环，上报 agent_crash 并按最长等待重启
+    max_restarts: 10
+    restart_window: 600
+
 # 是否开启采集,true为开启，false为不开启
 metrics:
 
@@ -77,6 +94,85 @@ metrics:
     # 开启之后需要填入路径，如果是当前路径直接写admin.conf,如果不是就写绝对路径
     config_path: ""
 
+  # 是否开启按挂载点采集磁盘容量、inode 和只读状态
+  disk:
+    enable: true
+    # 只采集这些文件系统类型，为空时采集除 proc、tmpfs、overlay 等虚拟文件系统外的全部
+    include_fstypes: []
+    exclude_fstypes: []
+    # 按挂载点过滤，支持通配符，如 ["/data*"]
+    include_mounts: []
+    exclude_mounts: []
+
+  # 是否开启采集磁盘 I/O 吞吐量、IOPS、平均等待、队列深度和利用率
+  diskio:
+    enable: true
+    # 按设备名过滤，支持通配符；loop* ram* 始终排除，exclude_devices 为额外排除的设备
+    include_devices: []
+    exclude_devices: []
+    # 是否同时采集分区，默认只采集整块磁盘
+    partitions: false
+
+  # 是否开启采集网卡收发速率、错误、丢包、链路状态和速率
+  net:
+    enable: true
+    # 按网卡名过滤，支持通配符；始终排除 lo veth* cali* flannel* cni*，exclude_interfaces 在此基础上追加
+    include_interfaces: []
+    exclude_interfaces: []
+
+  # 是否开启采集 cpu、memory、io 的压力阻塞信息（PSI），未配置时默认开启，内核不支持时自动跳过
+  psi:
+    enable: true
+
+  # 是否开启采集进程总数、线程数、僵尸进程，以及 CPU 和内存占用最高的进程
+  # 会上报进程的命令行，开启前请确认脱敏规则覆盖了本机进程参数中的敏感信息
+  top:
+    enable: false
+    # 每个排行上报的进程数
+    count: 10
+    # 额外的命令行脱敏正则，有捕获组时保留第一个捕获组（及第二个捕获组），其余替换为 ******
+    # URL 中的密码、password、token、secret、api_key、--requirepass、--auth、-pxxx 等始终脱敏，如 ["(--db-dsn=)\\S+"]
+    redact_patterns: []
+
+  # 是否开启进程检查，按进程名、命令行正则、pidfile 和用户匹配，上报是否运行、实例数、运行时长、内存、CPU、文件描述符数和线程数
+  process:
+    enable: false
+    # 配置了多个条件时需要同时满足，示例：
+    # - name: redis
+    #   process_name: redis-server
+    #   user: redis
+    # - name: order-service
+    #   cmdline: "java .*order-service.*\\.jar"
+    # - name: cron
+    #   pidfile: /var/run/crond.pid
+    watch: []
+
+  # 容器内运行时采集 agent 所在 cgroup 的 CPU 配额与限流、内存上限、OOM 和进程数上限
+  # host：不采集；container：始终采集；auto（默认）：检测到运行在容器中时采集
+  cgroup:
+    mode: auto
+
+# 本地状态与调试接口（/healthz、/status、/last/{source}、/loglevel、/debug/pprof）
+api:
+  enable: false
+  # 监听地址，只建议监听本机，如 127.0.0.1:9110 或 unix:/run/monitor-agent.sock
+  listen: "127.0.0.1:9110"
+  # 访问令牌，请求头 Authorization: Bearer <token>，TCP 监听时必须配置
+  token: ""
+
+# 日志配置
+log:
+  # 日志级别：debug、info、warn、error，运行中可发送 SIGUSR1 在该级别与 debug 之间切换（守护模式下会转发给工作进程）
+  level: info
+  # 输出格式：logfmt 或 json
+  format: logfmt
+  # 日志文件路径，为空时输出到 stderr
+  file: ""
+  # 单个文件最大大小（MB）、最多保留的历史文件数、历史文件最长保留天数
+  max_size: 100
+  max_backups: 5
+  max_age: 7
+
 # 加密盐，数据加密传输
 encrypted: ""`
 
@@ -92,21 +188,15 @@ encrypted: ""`
 			return config, err
 		}
 
-		log.Println("默认配置文件已创建，请修改后重新运行程序。")
+		slog.Warn("默认配置文件已创建，请修改后重新运行程序", "path", filePath)
 		return config, nil
 	}
 
 	// 如果配置文件存在，读取配置
-	file, err := os.Open(filePath)
+	config, err := ReadConfig(filePath)
 	if err != nil {
 		return config, err
 	}
-	defer func() { _ = file.Close() }()
-
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&config); err != nil {
-		return config, err
-	}
 
 	// 更新缓存
 	cachedConfig = &config
@@ -114,3 +204,18 @@ encrypted: ""`
 
 	return config, nil
 }
+
+// ReadConfig 读取指定的配置文件，不存在时返回错误而不创建默认配置，也不使用缓存
+func ReadConfig(path string) (ConfigFile, error) {
+	var config ConfigFile
+	file, err := os.Open(path)
+	if err != nil {
+		return config, err
+	}
+	defer func() { _ = file.Close() }()
+
+	if err := yaml.NewDecoder(file).Decode(&config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
@@ -0,0 +1,248 @@
+package Middleware
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level      string `yaml:"level"`       // 日志级别：debug、info、warn、error
+	Format     string `yaml:"format"`      // 输出格式：logfmt（默认）或 json
+	File       string `yaml:"file"`        // 日志文件路径，为空时输出到 stderr
+	MaxSize    int    `yaml:"max_size"`    // 单个日志文件最大大小（MB），超过后轮转
+	MaxBackups int    `yaml:"max_backups"` // 最多保留的历史日志文件数
+	MaxAge     int    `yaml:"max_age"`     // 历史日志文件最长保留天数
+}
+
+var (
+	// 全局日志级别，支持运行时修改
+	logLevel = new(slog.LevelVar)
+	// 配置文件中的日志级别，SIGUSR1 在该级别与 debug 之间切换
+	configuredLevel slog.Level
+
+	logOutputMutex sync.Mutex
+	logOutput      io.Writer = os.Stderr
+	logSignalOnce  sync.Once
+)
+
+// 解析日志级别
+func parseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("未知的日志级别: %s", level)
+	}
+}
+
+// SetupLogger 根据配置初始化全局日志，标准库 log 的输出也会转到该日志。
+// 受守护进程管理的工作进程始终输出到 stderr，由守护进程统一写入日志文件，避免两个进程同时轮转同一文件
+func SetupLogger(cfg LogConfig) error {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	var output io.Writer = os.Stderr
+	if cfg.File != "" && !isSupervised() {
+		writer, err := newRotateWriter(cfg)
+		if err != nil {
+			return err
+		}
+		output = writer
+	}
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "json":
+		handler = slog.NewJSONHandler(output, opts)
+	case "", "logfmt", "text":
+		handler = slog.NewTextHandler(output, opts)
+	default:
+		return fmt.Errorf("未知的日志格式: %s", cfg.Format)
+	}
+
+	logOutputMutex.Lock()
+	// 只关闭自己打开的日志文件，stderr 需要保留给运行时的 panic 输出
+	if previous, ok := logOutput.(*rotateWriter); ok && logOutput != output {
+		defer func() { _ = previous.Close() }()
+	}
+	logOutput = output
+	logOutputMutex.Unlock()
+
+	configuredLevel = level
+	logLevel.Set(level)
+	slog.SetDefault(slog.New(handler))
+	// 标准库 log 的输出按 info 级别写入，时间由 handler 记录
+	log.SetFlags(0)
+
+	logSignalOnce.Do(watchLogLevelSignal)
+	return nil
+}
+
+// LogOutput 当前日志输出目标，守护进程用它承接工作进程的输出
+func LogOutput() io.Writer {
+	logOutputMutex.Lock()
+	defer logOutputMutex.Unlock()
+	return logOutput
+}
+
+// SetLogLevel 运行时修改日志级别
+func SetLogLevel(level string) error {
+	parsed, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	logLevel.Set(parsed)
+	slog.Info("日志级别已修改", "level", parsed.String())
+	return nil
+}
+
+// LogLevel 当前日志级别
+func LogLevel() string {
+	return strings.ToLower(logLevel.Level().String())
+}
+
+// 收到 SIGUSR1 时在配置级别与 debug 之间切换
+func watchLogLevelSignal() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1)
+	go func() {
+		for range sigChan {
+			if logLevel.Level() == slog.LevelDebug && configuredLevel != slog.LevelDebug {
+				logLevel.Set(configuredLevel)
+			} else {
+				logLevel.Set(slog.LevelDebug)
+			}
+			slog.Warn("收到 SIGUSR1，日志级别已切换", "level", logLevel.Level().String())
+		}
+	}()
+}
+
+// 按大小轮转的日志文件，历史文件按数量和天数清理
+type rotateWriter struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	file       *os.File
+	size       int64
+}
+
+func newRotateWriter(cfg LogConfig) (*rotateWriter, error) {
+	path, err := filepath.Abs(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("解析日志文件路径失败: %v", err)
+	}
+	w := &rotateWriter{
+		path:       path,
+		maxSize:    int64(cfg.MaxSize) * 1024 * 1024,
+		maxBackups: cfg.MaxBackups,
+		maxAge:     time.Duration(cfg.MaxAge) * 24 * time.Hour,
+	}
+	if w.maxSize <= 0 {
+		w.maxSize = 100 * 1024 * 1024
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %v", err)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// 打开（或续写）日志文件
+func (w *rotateWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("读取日志文件信息失败: %v", err)
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.size+int64(len(p)) > w.maxSize && w.size > 0 {
+		if err := w.rotate(); err != nil {
+			// 轮转失败时继续写入当前文件，不丢日志
+			fmt.Fprintf(os.Stderr, "日志轮转失败: %v\n", err)
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// 将当前文件改名为 <file>.<时间戳>，重新打开新文件并清理过期的历史文件
+func (w *rotateWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	backup := w.path + "." + time.Now().Format("20060102-150405.000")
+	if err := os.Rename(w.path, backup); err != nil {
+		_ = w.open()
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.cleanup()
+	return nil
+}
+
+// 清理超出数量或天数的历史日志文件
+func (w *rotateWriter) cleanup() {
+	backups, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return
+	}
+	// 时间戳后缀按字典序即时间顺序，新的在前
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	for i, backup := range backups {
+		remove := w.maxBackups > 0 && i >= w.maxBackups
+		if !remove && w.maxAge > 0 {
+			if info, err := os.Stat(backup); err == nil && time.Since(info.ModTime()) > w.maxAge {
+				remove = true
+			}
+		}
+		if remove {
+			_ = os.Remove(backup)
+		}
+	}
+}
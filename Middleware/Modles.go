@@ -9,13 +9,13 @@ type FlatSystemInfo struct {
 	DiskUsed          uint64  `json:"disk_used"`           // 已用磁盘空间（字节）
 	DiskFree          uint64  `json:"disk_free"`           // 剩余磁盘空间（字节）
 	DiskUsedPercent   float64 `json:"disk_used_percent"`   // 磁盘使用百分比
-	MemoryTotal       uint64  `json:"memory_total"`        // 总内存（字节）
-	MemoryUsed        uint64  `json:"memory_used"`         // 已用内存（字节）
-	MemoryFree        uint64  `json:"memory_free"`         // 剩余内存（字节）
-	MemoryBuffered    uint64  `json:"memory_buffered"`     // 缓存内存（字节）
-	MemoryCached      uint64  `json:"memory_cached"`       // 被缓存的内存（字节）
-	MemoryShared      uint64  `json:"memory_shared"`       // 共享内存（字节）
-	MemoryAvailable   uint64  `json:"memory_available"`    // 可用内存（字节）
+	MemoryTotal       uint64  `json:"memory_total"`        // 总内存（KB）
+	MemoryUsed        uint64  `json:"memory_used"`         // 已用内存（KB），与 free 命令一致为总内存 - 可用内存
+	MemoryFree        uint64  `json:"memory_free"`         // 空闲内存（KB）
+	MemoryBuffered    uint64  `json:"memory_buffered"`     // 缓冲区内存（KB）
+	MemoryCached      uint64  `json:"memory_cached"`       // 页缓存（KB）
+	MemoryShared      uint64  `json:"memory_shared"`       // 共享内存（KB）
+	MemoryAvailable   uint64  `json:"memory_available"`    // 可用内存（KB）
 	MemoryUsedPercent float64 `json:"memory_used_percent"` // 内存使用百分比
 	CPULoad1          float64 `json:"cpu_load_1"`          // 1分钟CPU负载
 	CPULoad5          float64 `json:"cpu_load_5"`          // 5分钟CPU负载
@@ -23,8 +23,66 @@ type FlatSystemInfo struct {
 	HostName          string  `json:"hostName"`            // 主机名
 	CPUCount          int     `json:"cpu_count"`           // CPU 核心数
 	CPUModel          string  `json:"cpu_model"`           // CPU 型号
-	OSVersion         string  `json:"os_version"`          // 操作系统版本
+	OSVersion         string  `json:"os_version"`          // 操作系统版本（/etc/redhat-release 的内容，没有时为 os-release 的 PRETTY_NAME）
+	OSID              string  `json:"os_id"`               // 发行版标识（os-release 的 ID），如 centos、ubuntu
+	OSVersionID       string  `json:"os_version_id"`       // 发行版版本号（os-release 的 VERSION_ID）
 	KernelVersion     string  `json:"kernel_version"`      // 内核版本
+	Scope             string  `json:"scope"`               // 数据范围，固定为 host：容器内运行时同样是宿主机的数据，容器自身的限额和用量见 cgroup
+	InContainer       bool    `json:"in_container"`        // agent 是否运行在容器中
+
+	// CPU 各状态时间占比（百分比）。CPUPercent 沿用原有口径，不计 iowait、irq、softirq 和 steal
+	CPUUsage        float64    `json:"cpu_usage"` // 100 - idle - iowait，steal 计入使用率
+	CPUUser         float64    `json:"cpu_user"`
+	CPUNice         float64    `json:"cpu_nice"`
+	CPUSystem       float64    `json:"cpu_system"`
+	CPUIdle         float64    `json:"cpu_idle"`
+	CPUIowait       float64    `json:"cpu_iowait"`
+	CPUIrq          float64    `json:"cpu_irq"`
+	CPUSoftirq      float64    `json:"cpu_softirq"`
+	CPUSteal        float64    `json:"cpu_steal"`                // 被宿主机占用的时间，虚拟机超卖时升高
+	CPUCores        []CPUTimes `json:"cpu_cores"`                // 每个核心的时间占比
+	ContextSwitches float64    `json:"context_switches_per_sec"` // 每秒上下文切换次数
+	Interrupts      float64    `json:"interrupts_per_sec"`       // 每秒中断次数
+	Forks           float64    `json:"forks_per_sec"`            // 每秒创建的进程数
+
+	// 交换分区、内核内存和大页（KB，大页数量除外）
+	SwapTotal          uint64  `json:"swap_total"`
+	SwapFree           uint64  `json:"swap_free"`
+	SwapUsed           uint64  `json:"swap_used"`
+	SwapUsedPercent    float64 `json:"swap_used_percent"`
+	MemoryDirty        uint64  `json:"memory_dirty"`        // 等待写回磁盘的脏页
+	MemoryWriteback    uint64  `json:"memory_writeback"`    // 正在写回磁盘的页
+	MemorySlab         uint64  `json:"memory_slab"`         // 内核 slab 总量
+	MemorySReclaimable uint64  `json:"memory_sreclaimable"` // 可回收的 slab
+	MemorySUnreclaim   uint64  `json:"memory_sunreclaim"`   // 不可回收的 slab
+	HugePagesTotal     uint64  `json:"hugepages_total"`     // 大页总数（页）
+	HugePagesFree      uint64  `json:"hugepages_free"`      // 空闲大页（页）
+	HugePagesRsvd      uint64  `json:"hugepages_rsvd"`      // 已预留未分配的大页（页）
+	HugePageSize       uint64  `json:"hugepage_size"`       // 大页大小
+	CommitLimit        uint64  `json:"memory_commit_limit"` // 可提交内存上限
+	Committed          uint64  `json:"memory_committed"`    // 已提交内存（Committed_AS）
+
+	ProbeErrors []ProbeError `json:"probe_errors,omitempty"` // 失败的探测项，对应字段为零值，不代表真实取值
+}
+
+// ProbeError 单个探测项的失败原因
+type ProbeError struct {
+	Field string `json:"field"` // 探测项：cpu、disk、memory、load、hostname、cpu_count、cpu_model、os、kernel
+	Error string `json:"error"` // 失败原因
+}
+
+// CPUTimes 单个 CPU（或汇总）各状态的时间占比（百分比）
+type CPUTimes struct {
+	CPU     string  `json:"cpu"`   // cpu 表示汇总，cpu0、cpu1 表示各核心
+	Usage   float64 `json:"usage"` // 使用率：100 - idle - iowait
+	User    float64 `json:"user"`
+	Nice    float64 `json:"nice"`
+	System  float64 `json:"system"`
+	Idle    float64 `json:"idle"`
+	Iowait  float64 `json:"iowait"`
+	Irq     float64 `json:"irq"`
+	Softirq float64 `json:"softirq"`
+	Steal   float64 `json:"steal"`
 }
 
 // DomainInfo 用于保存域名、备注和证书信息的结构体
@@ -111,12 +169,199 @@ type ControllerResource struct {
 	ReplicaCount   int32  `json:"replica"`         // 副本数
 }
 
+// AgentCrash 守护进程检测到工作进程崩溃循环时上报的信息
+type AgentCrash struct {
+	HostName   string   `json:"hostName"`   // 主机名
+	Version    string   `json:"version"`    // 守护进程版本号
+	Reason     string   `json:"reason"`     // 最后一次退出原因（config_error、panic、runtime_error、signal）
+	ExitCode   int      `json:"exitCode"`   // 最后一次退出码，被信号终止时为 -1
+	Restarts   int      `json:"restarts"`   // 统计窗口内的重启次数
+	Window     int      `json:"window"`     // 统计窗口（秒）
+	NextDelay  int      `json:"nextDelay"`  // 下次重启前的等待时间（秒）
+	LastStderr []string `json:"lastStderr"` // 工作进程最后输出的 stderr 行
+}
+
+// DiskMount 单个挂载点的磁盘信息
+type DiskMount struct {
+	HostName           string  `json:"hostName"`              // 主机名
+	MountPoint         string  `json:"mount_point"`           // 挂载点
+	Device             string  `json:"device"`                // 挂载来源（设备）
+	FsType             string  `json:"fs_type"`               // 文件系统类型
+	Total              uint64  `json:"total"`                 // 总空间（字节）
+	Used               uint64  `json:"used"`                  // 已用空间（字节）
+	Free               uint64  `json:"free"`                  // 普通用户可用空间（字节）
+	UsedPercent        float64 `json:"used_percent"`          // 已用 / 总空间，与 hard 的 disk_used_percent 一致
+	UsedPercentOfAvail float64 `json:"used_percent_of_avail"` // 已用 / (已用 + 普通用户可用)，与 df 一致，不计入 root 保留空间
+	InodesTotal        uint64  `json:"inodes_total"`          // inode 总数
+	InodesUsed         uint64  `json:"inodes_used"`           // 已用 inode
+	InodesFree         uint64  `json:"inodes_free"`           // 剩余 inode
+	InodesUsedPercent  float64 `json:"inodes_used_percent"`   // inode 使用百分比
+	ReadOnly           bool    `json:"read_only"`             // 是否只读挂载
+}
+
+// DiskConfig 磁盘采集配置
+type DiskConfig struct {
+	Enable         bool     `yaml:"enable"`
+	IncludeFsTypes []string `yaml:"include_fstypes"` // 只采集这些文件系统类型，为空时采集除虚拟文件系统外的全部
+	ExcludeFsTypes []string `yaml:"exclude_fstypes"` // 排除的文件系统类型
+	IncludeMounts  []string `yaml:"include_mounts"`  // 只采集匹配的挂载点，支持通配符，如 /data*
+	ExcludeMounts  []string `yaml:"exclude_mounts"`  // 排除匹配的挂载点，支持通配符
+}
+
+// DiskIO 单个块设备的 I/O 信息（采样周期内的平均值）
+type DiskIO struct {
+	HostName         string  `json:"hostName"`            // 主机名
+	Device           string  `json:"device"`              // 设备名
+	ReadBytesPerSec  float64 `json:"read_bytes_per_sec"`  // 每秒读取字节数
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec"` // 每秒写入字节数
+	ReadIOPS         float64 `json:"read_iops"`           // 每秒完成的读请求数
+	WriteIOPS        float64 `json:"write_iops"`          // 每秒完成的写请求数
+	ReadAwait        float64 `json:"read_await"`          // 读请求平均耗时（毫秒）
+	WriteAwait       float64 `json:"write_await"`         // 写请求平均耗时（毫秒）
+	Await            float64 `json:"await"`               // 读写请求平均耗时（毫秒）
+	QueueDepth       float64 `json:"queue_depth"`         // 平均队列深度
+	UtilPercent      float64 `json:"util_percent"`        // 设备繁忙时间百分比
+	InFlight         uint64  `json:"in_flight"`           // 采样时刻进行中的请求数
+}
+
+// DiskIOConfig 磁盘 I/O 采集配置
+type DiskIOConfig struct {
+	Enable         bool     `yaml:"enable"`
+	IncludeDevices []string `yaml:"include_devices"` // 只采集匹配的设备，支持通配符，如 sd* nvme*
+	ExcludeDevices []string `yaml:"exclude_devices"` // 额外排除匹配的设备，loop* ram* 始终排除
+	Partitions     bool     `yaml:"partitions"`      // 是否同时采集分区，默认只采集整块磁盘
+}
+
+// NetInterface 单个网卡的流量信息（采样周期内的平均值）
+type NetInterface struct {
+	HostName        string  `json:"hostName"`           // 主机名
+	Interface       string  `json:"interface"`          // 网卡名
+	OperState       string  `json:"oper_state"`         // 运行状态（up、down、unknown 等）
+	LinkUp          bool    `json:"link_up"`            // 是否检测到链路
+	SpeedMbps       int     `json:"speed_mbps"`         // 协商速率（Mbps），未知时为 -1
+	RxBytesPerSec   float64 `json:"rx_bytes_per_sec"`   // 每秒接收字节数
+	RxPacketsPerSec float64 `json:"rx_packets_per_sec"` // 每秒接收包数
+	RxErrorsPerSec  float64 `json:"rx_errors_per_sec"`  // 每秒接收错误数
+	RxDropsPerSec   float64 `json:"rx_drops_per_sec"`   // 每秒接收丢包数
+	TxBytesPerSec   float64 `json:"tx_bytes_per_sec"`   // 每秒发送字节数
+	TxPacketsPerSec float64 `json:"tx_packets_per_sec"` // 每秒发送包数
+	TxErrorsPerSec  float64 `json:"tx_errors_per_sec"`  // 每秒发送错误数
+	TxDropsPerSec   float64 `json:"tx_drops_per_sec"`   // 每秒发送丢包数
+}
+
+// NetConfig 网卡采集配置
+type NetConfig struct {
+	Enable            bool     `yaml:"enable"`
+	IncludeInterfaces []string `yaml:"include_interfaces"` // 只采集匹配的网卡，支持通配符，如 eth* bond*
+	ExcludeInterfaces []string `yaml:"exclude_interfaces"` // 排除匹配的网卡，lo veth* cali* flannel* cni* 始终排除
+}
+
+// PSI 单个资源的压力阻塞信息（/proc/pressure），avg 为阻塞时间百分比
+type PSI struct {
+	HostName     string  `json:"hostName"`      // 主机名
+	Resource     string  `json:"resource"`      // 资源类型：cpu、memory、io
+	SomeAvg10    float64 `json:"some_avg10"`    // 至少一个任务阻塞的时间占比（10秒平均）
+	SomeAvg60    float64 `json:"some_avg60"`    // 同上（60秒平均）
+	SomeAvg300   float64 `json:"some_avg300"`   // 同上（300秒平均）
+	SomeStall    uint64  `json:"some_stall"`    // 采样周期内至少一个任务阻塞的时间（微秒）
+	FullAvg10    float64 `json:"full_avg10"`    // 全部非空闲任务同时阻塞的时间占比（10秒平均）
+	FullAvg60    float64 `json:"full_avg60"`    // 同上（60秒平均）
+	FullAvg300   float64 `json:"full_avg300"`   // 同上（300秒平均）
+	FullStall    uint64  `json:"full_stall"`    // 采样周期内全部任务同时阻塞的时间（微秒）
+	IntervalMsec int64   `json:"interval_msec"` // 采样周期（毫秒），首次采样为开机以来
+}
+
+// CgroupInfo agent 所在 cgroup（容器）的资源限制和使用量
+type CgroupInfo struct {
+	HostName            string  `json:"hostName"`              // 主机名（容器内为 Pod 名）
+	Version             int     `json:"cgroup_version"`        // cgroup 版本：1 或 2
+	Path                string  `json:"path"`                  // cgroup 路径
+	CPUQuota            float64 `json:"cpu_quota"`             // CPU 配额（核数），0 表示不限制
+	CPUUsage            float64 `json:"cpu_usage"`             // 采样周期内平均使用的核数，首次采样为 0
+	CPUPeriods          uint64  `json:"cpu_periods"`           // 累计 CFS 调度周期数
+	CPUThrottledPeriods uint64  `json:"cpu_throttled_periods"` // 累计被限流的周期数
+	CPUThrottledTime    uint64  `json:"cpu_throttled_usec"`    // 累计被限流的时间（微秒）
+	CPUThrottledPercent float64 `json:"cpu_throttled_percent"` // 采样周期内被限流的周期占比
+	MemoryMax           uint64  `json:"memory_max"`            // 内存上限（字节），0 表示不限制
+	MemoryCurrent       uint64  `json:"memory_current"`        // 当前内存使用量（字节）
+	MemoryUsedPercent   float64 `json:"memory_used_percent"`   // 内存使用量占上限的百分比，不限制时为 0
+	OOMEvents           uint64  `json:"oom_events"`            // 累计达到内存上限的次数，仅 cgroup v2
+	UnderOOM            bool    `json:"under_oom"`             // 当前是否处于 OOM 状态，仅 cgroup v1
+	OOMKills            uint64  `json:"oom_kills"`             // 累计被 OOM 杀死的进程数
+	PidsMax             uint64  `json:"pids_max"`              // 进程数上限，0 表示不限制
+	PidsCurrent         uint64  `json:"pids_current"`          // 当前进程数
+}
+
+// ProcessInfo 单个进程的资源使用
+type ProcessInfo struct {
+	PID           int     `json:"pid"`            // 进程号
+	PPID          int     `json:"ppid"`           // 父进程号
+	Name          string  `json:"name"`           // 进程名
+	User          string  `json:"user"`           // 运行用户
+	State         string  `json:"state"`          // 进程状态：R 运行、S 睡眠、D 不可中断、Z 僵尸 等
+	Cmdline       string  `json:"cmdline"`        // 命令行（已脱敏，过长时截断）
+	CPUPercent    float64 `json:"cpu_percent"`    // 采样周期内的 CPU 使用率，单核为 100%
+	RSS           uint64  `json:"rss"`            // 常驻内存（字节）
+	MemoryPercent float64 `json:"memory_percent"` // 常驻内存占总内存的百分比
+	Threads       int     `json:"threads"`        // 线程数
+}
+
+// TopProcesses 主机进程概况及 CPU、内存占用最高的进程
+type TopProcesses struct {
+	HostName  string        `json:"hostName"`   // 主机名
+	Processes int           `json:"processes"`  // 进程总数
+	Threads   int           `json:"threads"`    // 线程总数
+	Running   int           `json:"running"`    // 运行中（R）的进程数
+	Blocked   int           `json:"blocked"`    // 不可中断（D）的进程数
+	Zombies   int           `json:"zombies"`    // 僵尸进程数
+	TopCPU    []ProcessInfo `json:"top_cpu"`    // 按 CPU 使用率排序
+	TopMemory []ProcessInfo `json:"top_memory"` // 按常驻内存排序
+}
+
+// TopConfig 进程排行采集配置
+type TopConfig struct {
+	Enable         bool     `yaml:"enable"`
+	Count          int      `yaml:"count"`           // 每个排行上报的进程数，默认 10
+	RedactPatterns []string `yaml:"redact_patterns"` // 额外的命令行脱敏正则，URL 中的密码和 password、token、--requirepass、-pxxx 等参数值始终脱敏
+}
+
+// ProcessStatus 单个进程检查的结果，资源使用为全部匹配进程之和
+type ProcessStatus struct {
+	HostName   string  `json:"hostName"`        // 主机名
+	Name       string  `json:"name"`            // 检查名称
+	Up         bool    `json:"up"`              // 是否有匹配的进程在运行
+	Instances  int     `json:"instances"`       // 匹配的进程数
+	PIDs       []int   `json:"pids"`            // 匹配的进程号
+	Uptime     int64   `json:"uptime"`          // 最早启动的进程已运行的时间（秒）
+	RSS        uint64  `json:"rss"`             // 常驻内存（字节）
+	CPUPercent float64 `json:"cpu_percent"`     // 采样周期内的 CPU 使用率，单核为 100%
+	OpenFDs    int     `json:"open_fds"`        // 打开的文件描述符数，无权限读取时为 -1
+	Threads    int     `json:"threads"`         // 线程数
+	Error      string  `json:"error,omitempty"` // 检查配置有误时的错误信息，此时 up 为 false
+}
+
+// ProcessWatch 进程检查的匹配条件，配置了多个条件时需要同时满足
+type ProcessWatch struct {
+	Name        string `yaml:"name"`         // 检查名称
+	ProcessName string `yaml:"process_name"` // 进程名，支持通配符，匹配 comm 或第一个参数的文件名
+	Cmdline     string `yaml:"cmdline"`      // 命令行正则
+	Pidfile     string `yaml:"pidfile"`      // pidfile 路径
+	User        string `yaml:"user"`         // 运行用户（用户名或 UID）
+}
+
 // 配置结构体
 type ConfigFile struct {
 	Agent struct {
 		Project    string `yaml:"project"`
 		MetricsURL string `yaml:"metrics_url"`
 		AutoUpdate bool   `yaml:"auto_update"`
+		HostRoot   string `yaml:"host_root"` // 宿主机根目录的挂载点，容器内采集宿主机指标时使用，如 /host
+		Supervisor struct {
+			BackoffMin    int `yaml:"backoff_min"`    // 重启退避初始等待（秒）
+			BackoffMax    int `yaml:"backoff_max"`    // 重启退避最大等待（秒）
+			MaxRestarts   int `yaml:"max_restarts"`   // 统计窗口内允许的最大重启次数
+			RestartWindow int `yaml:"restart_window"` // 重启次数统计窗口（秒）
+		} `yaml:"supervisor"`
 	} `yaml:"agent"`
 	Metrics struct {
 		Ssl    struct{ Enable bool } `yaml:"ssl"`
@@ -126,6 +371,26 @@ type ConfigFile struct {
 			Enable     bool   `yaml:"enable"`
 			ConfigPath string `yaml:"config_path"`
 		} `yaml:"k8s"`
+		Disk   DiskConfig   `yaml:"disk"`
+		DiskIO DiskIOConfig `yaml:"diskio"`
+		Net    NetConfig    `yaml:"net"`
+		Psi    struct {
+			Enable *bool `yaml:"enable"` // 未配置时默认开启，内核不支持 PSI 时自动跳过
+		} `yaml:"psi"`
+		Top     TopConfig `yaml:"top"`
+		Process struct {
+			Enable bool           `yaml:"enable"`
+			Watch  []ProcessWatch `yaml:"watch"`
+		} `yaml:"process"`
+		Cgroup struct {
+			Mode string `yaml:"mode"` // host、container 或 auto，为空时同 auto
+		} `yaml:"cgroup"`
 	} `yaml:"metrics"`
-	Encrypted string `yaml:"encrypted"` // 加密密钥
+	Api struct {
+		Enable bool   `yaml:"enable"`
+		Listen string `yaml:"listen"` // 监听地址，如 127.0.0.1:9110 或 unix:/run/monitor-agent.sock
+		Token  string `yaml:"token"`  // 访问令牌，TCP 监听时必须配置
+	} `yaml:"api"`
+	Log       LogConfig `yaml:"log"`       // 日志配置
+	Encrypted string    `yaml:"encrypted"` // 加密密钥
 }
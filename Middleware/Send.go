@@ -7,6 +7,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"time"
@@ -40,6 +41,23 @@ func encrypt(data []byte, key []byte) ([]byte, error) {
 	return ciphertext, nil
 }
 
+// 解密数据，与 encrypt 相反：前 NonceSize 字节为随机数
+func decrypt(data []byte, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("数据长度 %d 小于随机数长度 %d", len(data), gcm.NonceSize())
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
 // 压缩数据
 func compress(data []byte) ([]byte, error) {
 	var buf bytes.Buffer
@@ -53,18 +71,70 @@ func compress(data []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// 发送数据到指定的 URL
-func SendData(url string, project string, data interface{}, key []byte, source string) error {
-	// 创建要发送的数据结构
+// 解压数据
+func decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	return io.ReadAll(reader)
+}
+
+// DecodePayload 还原 SendData 发送的请求体：解密、解压、解析并校验 SendDataType，
+// 同时返回解压后的原始 JSON
+func DecodePayload(body []byte, key []byte) (SendDataType, []byte, error) {
+	var sendData SendDataType
+
+	compressedData, err := decrypt(body, key)
+	if err != nil {
+		return sendData, nil, fmt.Errorf("解密失败（密钥不一致或数据损坏）: %v", err)
+	}
+	jsonData, err := decompress(compressedData)
+	if err != nil {
+		return sendData, nil, fmt.Errorf("解压失败: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	if err := decoder.Decode(&sendData); err != nil {
+		return sendData, jsonData, fmt.Errorf("解析 JSON 失败: %v", err)
+	}
+	if err := validateSendData(sendData); err != nil {
+		return sendData, jsonData, err
+	}
+	return sendData, jsonData, nil
+}
+
+// 校验数据结构的必填字段
+func validateSendData(sendData SendDataType) error {
+	if sendData.SOURCE == "" {
+		return fmt.Errorf("数据结构无效: 缺少 source")
+	}
+	if sendData.Timestamp <= 0 {
+		return fmt.Errorf("数据结构无效: timestamp 无效 %d", sendData.Timestamp)
+	}
+	if sendData.Data == nil {
+		return fmt.Errorf("数据结构无效: 缺少 data")
+	}
+	return nil
+}
+
+// MarshalSendData 构建要发送的数据结构并序列化，即加密压缩前的原始内容
+func MarshalSendData(project string, data interface{}, source string) ([]byte, error) {
 	sendData := SendDataType{
 		PROJECT:   project,
 		Data:      data,
 		Timestamp: time.Now().UnixMilli(),
 		SOURCE:    source,
 	}
+	return json.Marshal(sendData)
+}
 
-	// 序列化数据
-	jsonData, err := json.Marshal(sendData)
+// 发送数据到指定的 URL
+func SendData(url string, project string, data interface{}, key []byte, source string) error {
+	// 创建要发送的数据结构并序列化
+	jsonData, err := MarshalSendData(project, data, source)
 	if err != nil {
 		return err
 	}
@@ -91,5 +161,8 @@ func SendData(url string, project string, data interface{}, key []byte, source s
 	// 必须读取并丢弃 response body，否则连接无法复用
 	_, _ = io.Copy(io.Discard, resp.Body)
 
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("服务端返回状态码: %d", resp.StatusCode)
+	}
 	return nil
 }
@@ -0,0 +1,209 @@
+package Middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 状态文件名，位于可执行文件同目录，供 status 子命令读取
+const statusFileName = "agent.status"
+
+// SourceStatus 单个数据来源的发送状态
+type SourceStatus struct {
+	LastSend    time.Time `json:"lastSend"`            // 最近一次发送时间
+	LastSuccess time.Time `json:"lastSuccess"`         // 最近一次发送成功时间，从未成功时为零值
+	LastError   string    `json:"lastError,omitempty"` // 最近一次发送失败原因，成功后清空
+	Sends       int       `json:"sends"`               // 累计发送次数
+	Errors      int       `json:"errors"`              // 累计失败次数
+}
+
+// CollectorStatus 单个采集任务的运行状态
+type CollectorStatus struct {
+	Interval     string    `json:"interval"`            // 采集周期
+	LastRun      time.Time `json:"lastRun"`             // 最近一次运行时间
+	LastDuration string    `json:"lastDuration"`        // 最近一次运行耗时
+	LastError    string    `json:"lastError,omitempty"` // 最近一次运行失败原因，成功后清空
+	Runs         int       `json:"runs"`                // 累计运行次数
+	Errors       int       `json:"errors"`              // 累计失败次数
+}
+
+// AgentStatus 工作进程运行状态
+type AgentStatus struct {
+	PID        int                        `json:"pid"`        // 工作进程 PID
+	Version    string                     `json:"version"`    // 版本号
+	StartedAt  time.Time                  `json:"startedAt"`  // 启动时间
+	UpdatedAt  time.Time                  `json:"updatedAt"`  // 状态更新时间
+	ConfigPath string                     `json:"configPath"` // 配置文件绝对路径
+	Sources    map[string]SourceStatus    `json:"sources"`    // 各数据来源的发送状态
+	Collectors map[string]CollectorStatus `json:"collectors"` // 各采集任务的运行状态
+}
+
+var (
+	statusMutex       sync.RWMutex
+	agentStarted      = time.Now()
+	sourceStatuses    = map[string]SourceStatus{}
+	collectorStatuses = map[string]CollectorStatus{}
+	lastPayloads      = map[string]SendDataType{}
+)
+
+// RecordSend 记录一次数据发送结果
+func RecordSend(source string, err error) {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+
+	now := time.Now()
+	status := sourceStatuses[source]
+	status.LastSend = now
+	status.Sends++
+	if err != nil {
+		status.LastError = err.Error()
+		status.Errors++
+	} else {
+		status.LastSuccess = now
+		status.LastError = ""
+	}
+	sourceStatuses[source] = status
+}
+
+// RecordCollect 记录一次采集任务的运行结果
+func RecordCollect(name string, interval time.Duration, start time.Time, duration time.Duration, err error) {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+
+	status := collectorStatuses[name]
+	status.Interval = interval.String()
+	status.LastRun = start
+	status.LastDuration = duration.String()
+	status.Runs++
+	if err != nil {
+		status.LastError = err.Error()
+		status.Errors++
+	} else {
+		status.LastError = ""
+	}
+	collectorStatuses[name] = status
+}
+
+// RecordPayload 保存某个数据来源最近一次采集到的数据
+func RecordPayload(project string, source string, data interface{}) {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+
+	lastPayloads[source] = SendDataType{
+		PROJECT:   project,
+		Data:      data,
+		Timestamp: time.Now().UnixMilli(),
+		SOURCE:    source,
+	}
+}
+
+// LastPayload 获取某个数据来源最近一次采集到的数据
+func LastPayload(source string) (SendDataType, bool) {
+	statusMutex.RLock()
+	defer statusMutex.RUnlock()
+
+	payload, ok := lastPayloads[source]
+	return payload, ok
+}
+
+// SnapshotStatus 获取当前运行状态的副本
+func SnapshotStatus(version string) AgentStatus {
+	statusMutex.RLock()
+	defer statusMutex.RUnlock()
+
+	sources := make(map[string]SourceStatus, len(sourceStatuses))
+	for source, status := range sourceStatuses {
+		sources[source] = status
+	}
+	collectors := make(map[string]CollectorStatus, len(collectorStatuses))
+	for name, status := range collectorStatuses {
+		collectors[name] = status
+	}
+
+	configPath, err := filepath.Abs(ConfigPath)
+	if err != nil {
+		configPath = ConfigPath
+	}
+
+	return AgentStatus{
+		PID:        os.Getpid(),
+		Version:    version,
+		StartedAt:  agentStarted,
+		UpdatedAt:  time.Now(),
+		ConfigPath: configPath,
+		Sources:    sources,
+		Collectors: collectors,
+	}
+}
+
+// SummarizeSends 各数据来源最近一次发送结果的简要描述，如 "hard=ok k8s=error"
+func SummarizeSends() string {
+	statusMutex.RLock()
+	defer statusMutex.RUnlock()
+
+	sources := make([]string, 0, len(sourceStatuses))
+	for source := range sourceStatuses {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	parts := make([]string, 0, len(sources))
+	for _, source := range sources {
+		result := "ok"
+		if sourceStatuses[source].LastError != "" {
+			result = "error"
+		}
+		parts = append(parts, source+"="+result)
+	}
+	if len(parts) == 0 {
+		return "暂无发送记录"
+	}
+	return strings.Join(parts, " ")
+}
+
+// StatusFilePath 状态文件路径
+func StatusFilePath() string {
+	exePath, err := ResolveExecutable()
+	if err != nil {
+		return statusFileName
+	}
+	return filepath.Join(filepath.Dir(exePath), statusFileName)
+}
+
+// WriteStatusFile 将当前运行状态写入状态文件
+func WriteStatusFile(version string) error {
+	data, err := json.MarshalIndent(SnapshotStatus(version), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := StatusFilePath()
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("写入状态文件失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("写入状态文件失败: %v", err)
+	}
+	return nil
+}
+
+// ReadStatusFile 读取状态文件
+func ReadStatusFile() (AgentStatus, error) {
+	var status AgentStatus
+	data, err := os.ReadFile(StatusFilePath())
+	if err != nil {
+		return status, err
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return status, fmt.Errorf("解析状态文件失败: %v", err)
+	}
+	return status, nil
+}
@@ -0,0 +1,320 @@
+package Middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 更新协议：
+//  1. 工作进程下载新版本到可执行文件旁的 <exe>.new 并校验
+//  2. 受守护进程管理时，工作进程写入 restart.flag 后正常退出
+//  3. 守护进程检测到标志文件后执行替换（旧版本备份为 <exe>.old）并重启工作进程
+//  4. 新版本启动后短时间内异常退出，守护进程自动回滚到 <exe>.old
+const (
+	SupervisedEnv   = "AGENT_SUPERVISED" // 守护进程启动工作进程时设置的环境变量
+	restartFlagName = "restart.flag"
+	rejectedName    = "rejected.version" // 启动失败被回滚的版本号，不再自动更新到该版本
+	accessWriteOK   = 0x2                // access(2) 的写权限检查标志，syscall 包未导出
+)
+
+// UpdateStaged 受守护进程管理时，新版本准备就绪后通知工作进程退出
+var UpdateStaged = make(chan struct{}, 1)
+
+// 是否由守护进程启动
+func isSupervised() bool {
+	return os.Getenv(SupervisedEnv) == "1"
+}
+
+// ResolveExecutable 获取当前可执行文件的真实路径（跟随软链接），更新文件写在它的旁边
+func ResolveExecutable() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("获取可执行文件路径失败: %v", err)
+	}
+	realPath, err := filepath.EvalSymlinks(exePath)
+	if err != nil {
+		return "", fmt.Errorf("解析可执行文件路径失败: %v", err)
+	}
+	return realPath, nil
+}
+
+// 待替换的新版本路径
+func stagedPath(exePath string) string {
+	return exePath + ".new"
+}
+
+// 替换前的旧版本备份路径
+func backupPath(exePath string) string {
+	return exePath + ".old"
+}
+
+// RestartFlagPath 重启标志文件路径，与可执行文件同目录
+func RestartFlagPath(exePath string) string {
+	return filepath.Join(filepath.Dir(exePath), restartFlagName)
+}
+
+// 被回滚的版本号文件路径，与重启标志同目录
+func rejectedPath(exePath string) string {
+	return filepath.Join(filepath.Dir(exePath), rejectedName)
+}
+
+// RejectVersion 记录启动失败被回滚的版本，避免回滚后的旧版本再次下载同一版本
+func RejectVersion(exePath string, version string) error {
+	if err := os.WriteFile(rejectedPath(exePath), []byte(version+"\n"), 0644); err != nil {
+		return fmt.Errorf("记录回滚版本失败: %v", err)
+	}
+	return nil
+}
+
+// 远程版本是否为已回滚的版本，远程发布更新的版本后自动恢复更新
+func isRejectedVersion(version float64) bool {
+	exePath, err := ResolveExecutable()
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(rejectedPath(exePath))
+	if err != nil {
+		return false
+	}
+	rejected, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	return err == nil && rejected == version
+}
+
+// 检查目录是否可写，只读镜像（如不可变容器）下给出明确错误，避免白白下载
+func checkWritable(dir string) error {
+	if err := syscall.Access(dir, accessWriteOK); err != nil {
+		return writableError(dir, err)
+	}
+	return nil
+}
+
+// 将目录不可写的底层错误转换为可读的提示
+func writableError(dir string, err error) error {
+	switch {
+	case errors.Is(err, syscall.EROFS):
+		return fmt.Errorf("目录 %s 位于只读文件系统，无法自动更新（不可变镜像请通过重新发布镜像升级）: %w", dir, err)
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return fmt.Errorf("目录 %s 没有写权限，无法自动更新: %w", dir, err)
+	default:
+		return fmt.Errorf("目录 %s 不可写: %w", dir, err)
+	}
+}
+
+// 将内容写入目标同目录的临时文件，落盘后原子改名为目标，权限和属主与 ref 保持一致
+func writeBinary(body io.Reader, dest string, ref os.FileInfo) error {
+	dir := filepath.Dir(dest)
+
+	// 临时文件与目标在同一目录，保证 rename 在同一文件系统内是原子的
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(dest)+"-*")
+	if err != nil {
+		return writableError(dir, err)
+	}
+	tmpPath := tmp.Name()
+	cleanup := func() { _ = os.Remove(tmpPath) }
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+		cleanup()
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		cleanup()
+		return fmt.Errorf("同步文件到磁盘失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return fmt.Errorf("关闭文件失败: %v", err)
+	}
+
+	// 保留原文件的权限和属主
+	if err := os.Chmod(tmpPath, ref.Mode().Perm()); err != nil {
+		cleanup()
+		return fmt.Errorf("设置文件权限失败: %v", err)
+	}
+	if stat, ok := ref.Sys().(*syscall.Stat_t); ok {
+		if err := os.Lchown(tmpPath, int(stat.Uid), int(stat.Gid)); err != nil {
+			// 非 root 运行时无法修改属主，新文件归当前用户所有，不影响执行
+			slog.Warn("保留文件属主失败", "path", dest, "err", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, dest); err != nil {
+		cleanup()
+		return fmt.Errorf("替换文件失败: %v", err)
+	}
+
+	// 同步目录项，保证断电后 rename 不丢失
+	return syncDir(dir)
+}
+
+// 同步目录到磁盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("打开目录失败: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("同步目录失败: %v", err)
+	}
+	return nil
+}
+
+// 下载新版本到 <exe>.new，服务端提供 sha256 时同时校验摘要
+func stageBinary(url string, exePath string) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return fmt.Errorf("读取可执行文件信息失败: %v", err)
+	}
+
+	// 二进制较大，不使用带 30 秒超时的全局客户端
+	resp, err := http.Get(url + "/agent/agent")
+	if err != nil {
+		return fmt.Errorf("下载失败: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载失败，HTTP状态码: %d", resp.StatusCode)
+	}
+
+	hash := sha256.New()
+	staged := stagedPath(exePath)
+	if err := writeBinary(io.TeeReader(resp.Body, hash), staged, info); err != nil {
+		return err
+	}
+
+	expected, err := getChecksumFromServer(url + "/agent/agent.sha256")
+	if err != nil {
+		_ = os.Remove(staged)
+		return err
+	}
+	if expected != "" && expected != hex.EncodeToString(hash.Sum(nil)) {
+		_ = os.Remove(staged)
+		return fmt.Errorf("新版本 sha256 校验失败，期望 %s", expected)
+	}
+	return nil
+}
+
+// 获取新版本的 sha256 摘要，服务端未提供时返回空字符串
+func getChecksumFromServer(url string) (string, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("获取 sha256 失败: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("获取 sha256 失败，HTTP状态码: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", fmt.Errorf("读取 sha256 失败: %v", err)
+	}
+	// 兼容 sha256sum 输出格式："<hash>  agent"
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// 运行新版本的 -version，确认可以执行且版本号与服务端一致
+func verifyBinary(path string, version float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "-version").Output()
+	if err != nil {
+		return fmt.Errorf("新版本无法运行: %v", err)
+	}
+	got, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return fmt.Errorf("解析新版本号失败: %v", err)
+	}
+	if got != version {
+		return fmt.Errorf("新版本号 %.2f 与服务端版本 %.2f 不一致", got, version)
+	}
+	return nil
+}
+
+// 写入重启标志文件，通知守护进程执行替换
+func writeRestartFlag(exePath string, version float64) error {
+	flagPath := RestartFlagPath(exePath)
+	tmpPath := flagPath + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strconv.FormatFloat(version, 'f', -1, 64)+"\n"), 0644); err != nil {
+		return fmt.Errorf("写入重启标志失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, flagPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("写入重启标志失败: %v", err)
+	}
+	return nil
+}
+
+// TakeRestartFlag 读取并删除重启标志，返回是否存在待应用的更新
+func TakeRestartFlag(exePath string) (string, bool) {
+	flagPath := RestartFlagPath(exePath)
+	data, err := os.ReadFile(flagPath)
+	if err != nil {
+		return "", false
+	}
+	_ = os.Remove(flagPath)
+	if _, err := os.Stat(stagedPath(exePath)); err != nil {
+		slog.Warn("重启标志存在但新版本文件缺失，忽略", "err", err)
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// ApplyUpdate 用 <exe>.new 替换当前版本，旧版本以硬链接备份为 <exe>.old。
+// 文件系统不支持硬链接时改为把旧版本改名为 <exe>.old；替换失败时删除 <exe>.new，下次检查时重新下载
+func ApplyUpdate(exePath string) error {
+	staged := stagedPath(exePath)
+	backup := backupPath(exePath)
+	_ = os.Remove(backup)
+
+	linked := true
+	if err := os.Link(exePath, backup); err != nil {
+		slog.Warn("硬链接备份旧版本失败，改为重命名", "err", err)
+		if err := os.Rename(exePath, backup); err != nil {
+			_ = os.Remove(staged)
+			return fmt.Errorf("备份旧版本失败: %v", err)
+		}
+		linked = false
+	}
+	if err := os.Rename(staged, exePath); err != nil {
+		// 旧版本已被改名时恢复原路径
+		if !linked {
+			_ = os.Rename(backup, exePath)
+		}
+		_ = os.Remove(staged)
+		return fmt.Errorf("替换文件失败: %v", err)
+	}
+	return syncDir(filepath.Dir(exePath))
+}
+
+// RollbackUpdate 用 <exe>.old 恢复上一个版本
+func RollbackUpdate(exePath string) error {
+	if err := os.Rename(backupPath(exePath), exePath); err != nil {
+		return fmt.Errorf("回滚失败: %v", err)
+	}
+	return syncDir(filepath.Dir(exePath))
+}
@@ -0,0 +1,216 @@
+package Receiver
+
+import (
+	"agent/Middleware"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// 单个请求体的最大大小
+const maxBodySize = 64 << 20
+
+// Options 参考接收端的启动参数
+type Options struct {
+	Listen  string // 监听地址
+	Key     string // 与 agent 一致的加密密钥
+	DataDir string // 接收到的数据按来源写入 <DataDir>/<source>.jsonl
+	Binary  string // 提供给 /agent/agent 的新版本二进制，为空时不提供更新
+	Version string // /version 返回的版本号，为空时不提供更新
+}
+
+// 各数据来源对应的数据结构，接收时按结构严格校验，未列出的来源只校验外层结构
+var sourceTypes = map[string]func() interface{}{
+	"hard":          func() interface{} { return &[]Middleware.FlatSystemInfo{} },
+	"heart":         func() interface{} { return &[]Middleware.HeartSource{} },
+	"nginx":         func() interface{} { return &[]Middleware.NginxStatus{} },
+	"harbor":        func() interface{} { return &[]Middleware.HarborInfo{} },
+	"k8s":           func() interface{} { return &[]Middleware.ContainerResource{} },
+	"k8sController": func() interface{} { return &[]map[string]interface{}{} },
+	"ssl":           func() interface{} { return &[]Middleware.DomainInfo{} },
+	"disk":          func() interface{} { return &[]Middleware.DiskMount{} },
+	"diskio":        func() interface{} { return &[]Middleware.DiskIO{} },
+	"net":           func() interface{} { return &[]Middleware.NetInterface{} },
+	"psi":           func() interface{} { return &[]Middleware.PSI{} },
+	"cgroup":        func() interface{} { return &[]Middleware.CgroupInfo{} },
+	"top":           func() interface{} { return &[]Middleware.TopProcesses{} },
+	"process":       func() interface{} { return &[]Middleware.ProcessStatus{} },
+	"agent_crash":   func() interface{} { return &[]Middleware.AgentCrash{} },
+}
+
+// 数据来源只允许作为文件名的安全字符
+var validSource = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// 接收端
+type receiver struct {
+	opts  Options
+	mu    sync.Mutex
+	files map[string]*os.File
+}
+
+// Run 启动参考接收端，阻塞直到监听失败
+func Run(opts Options) error {
+	if opts.Key == "" {
+		return fmt.Errorf("未指定加密密钥")
+	}
+	if err := os.MkdirAll(opts.DataDir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	r := &receiver{opts: opts, files: make(map[string]*os.File)}
+	defer r.close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /metrics_data", r.handleMetrics)
+	mux.HandleFunc("GET /version", r.handleVersion)
+	mux.HandleFunc("GET /agent/agent", r.handleBinary)
+	mux.HandleFunc("GET /agent/agent.sha256", r.handleChecksum)
+
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %v", opts.Listen, err)
+	}
+	slog.Info("参考接收端已启动", "listen", listener.Addr().String(), "dir", opts.DataDir, "version", opts.Version)
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return server.Serve(listener)
+}
+
+// 外层结构，data 保留原始 JSON 以便按来源严格校验
+type envelope struct {
+	Project   string          `json:"project"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp int64           `json:"timestamp"`
+	Source    string          `json:"source"`
+}
+
+// 校验数据内容是否符合该来源的数据结构
+func validateData(source string, data json.RawMessage) error {
+	newValue, ok := sourceTypes[source]
+	if !ok {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(newValue()); err != nil {
+		return fmt.Errorf("数据与 %s 的结构不一致: %v", source, err)
+	}
+	return nil
+}
+
+func (r *receiver) handleMetrics(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, jsonData, err := Middleware.DecodePayload(body, []byte(r.opts.Key))
+	if err != nil {
+		slog.Warn("请求体解码失败", "remote", req.RemoteAddr, "size", len(body), "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var env envelope
+	if err := json.Unmarshal(jsonData, &env); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !validSource.MatchString(env.Source) {
+		http.Error(w, "数据来源名称无效", http.StatusBadRequest)
+		return
+	}
+	if err := validateData(env.Source, env.Data); err != nil {
+		slog.Warn("数据校验失败", "source", env.Source, "project", env.Project, "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := sourceTypes[env.Source]; !ok {
+		slog.Warn("未知的数据来源，仅校验外层结构", "source", env.Source)
+	}
+
+	if err := r.store(env.Source, jsonData); err != nil {
+		slog.Error("保存数据失败", "source", env.Source, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.Debug("已接收数据", "source", env.Source, "project", env.Project, "size", len(body))
+	w.WriteHeader(http.StatusOK)
+}
+
+// 按数据来源追加写入 JSONL 文件
+func (r *receiver) store(source string, jsonData []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, ok := r.files[source]
+	if !ok {
+		var err error
+		file, err = os.OpenFile(filepath.Join(r.opts.DataDir, source+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		r.files[source] = file
+	}
+	line := append(bytes.TrimSpace(jsonData), '\n')
+	_, err := file.Write(line)
+	return err
+}
+
+func (r *receiver) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, file := range r.files {
+		_ = file.Close()
+	}
+}
+
+// 版本号，与 agent 的 CheckVersion 约定一致为纯文本
+func (r *receiver) handleVersion(w http.ResponseWriter, req *http.Request) {
+	if r.opts.Version == "" {
+		http.NotFound(w, req)
+		return
+	}
+	_, _ = io.WriteString(w, r.opts.Version)
+}
+
+func (r *receiver) handleBinary(w http.ResponseWriter, req *http.Request) {
+	if r.opts.Binary == "" {
+		http.NotFound(w, req)
+		return
+	}
+	http.ServeFile(w, req, r.opts.Binary)
+}
+
+// 新版本的 sha256，格式与 sha256sum 输出一致
+func (r *receiver) handleChecksum(w http.ResponseWriter, req *http.Request) {
+	if r.opts.Binary == "" {
+		http.NotFound(w, req)
+		return
+	}
+	file, err := os.Open(r.opts.Binary)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(hash.Sum(nil)), filepath.Base(r.opts.Binary))
+}
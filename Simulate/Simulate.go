@@ -0,0 +1,325 @@
+package Simulate
+
+import (
+	"agent/Collect"
+	"agent/Middleware"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options 压测参数
+type Options struct {
+	Agents     int           // 虚拟 agent 数量
+	Projects   int           // 虚拟 agent 平均分配到的项目数量
+	MetricsURL string        // 服务端地址，与配置文件的 metrics_url 一致
+	Key        string        // 加密密钥
+	Version    string        // 心跳上报的版本号
+	Duration   time.Duration // 运行时长，0 表示直到 ctx 取消
+	Report     time.Duration // 输出统计的间隔
+	Spread     bool          // 打散各虚拟 agent 的发送时间；默认与真实 agent 一样按整点对齐
+	Output     io.Writer     // 统计输出
+}
+
+// 模拟的数据来源，发送周期与真实采集任务一致
+var sources = []string{"hard", "heart", "k8s", "ssl"}
+
+// 单个数据来源的发送统计
+type counter struct {
+	sends      atomic.Int64
+	errors     atomic.Int64
+	latency    atomic.Int64 // 累计耗时（纳秒）
+	maxLatency atomic.Int64 // 最大耗时（纳秒）
+
+	mu        sync.Mutex
+	lastError string
+}
+
+func (c *counter) record(duration time.Duration, err error) {
+	c.sends.Add(1)
+	c.latency.Add(int64(duration))
+	for {
+		max := c.maxLatency.Load()
+		if int64(duration) <= max || c.maxLatency.CompareAndSwap(max, int64(duration)) {
+			break
+		}
+	}
+	if err != nil {
+		c.errors.Add(1)
+		c.mu.Lock()
+		c.lastError = err.Error()
+		c.mu.Unlock()
+	}
+}
+
+// 统计快照，用于计算区间内的增量
+type snapshot struct {
+	sends, errors, latency int64
+}
+
+func (c *counter) snapshot() snapshot {
+	return snapshot{sends: c.sends.Load(), errors: c.errors.Load(), latency: c.latency.Load()}
+}
+
+// Run 启动 N 个虚拟 agent，通过 Middleware.SendData 按真实周期发送合成数据，定期输出吞吐量和错误率
+func Run(ctx context.Context, opts Options) error {
+	if opts.Agents <= 0 {
+		return fmt.Errorf("虚拟 agent 数量必须大于 0")
+	}
+	if opts.Projects <= 0 {
+		opts.Projects = 1
+	}
+	if opts.Report <= 0 {
+		opts.Report = 10 * time.Second
+	}
+	if opts.MetricsURL == "" || opts.Key == "" {
+		return fmt.Errorf("未指定服务端地址或加密密钥")
+	}
+	version, err := strconv.ParseFloat(opts.Version, 64)
+	if err != nil {
+		return fmt.Errorf("转换版本号失败: %v", err)
+	}
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	counters := make(map[string]*counter, len(sources))
+	for _, source := range sources {
+		counters[source] = &counter{}
+	}
+
+	started := time.Now()
+	_, _ = fmt.Fprintf(opts.Output, "启动 %d 个虚拟 agent（%d 个项目），服务端 %s\n", opts.Agents, opts.Projects, opts.MetricsURL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Agents; i++ {
+		a := newAgent(i, opts.Projects, version)
+		for j, source := range sources {
+			// 每个发送协程独立的随机数生成器（rand.Rand 不是并发安全的）
+			r := rand.New(rand.NewSource(int64(i*len(sources) + j)))
+			wg.Add(1)
+			go func(source string) {
+				defer wg.Done()
+				a.run(ctx, opts, source, r, counters[source])
+			}(source)
+		}
+	}
+
+	ticker := time.NewTicker(opts.Report)
+	defer ticker.Stop()
+	last := make(map[string]snapshot, len(sources))
+	lastReport := started
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			printSummary(opts.Output, counters, time.Since(started))
+			return nil
+		case now := <-ticker.C:
+			printInterval(opts.Output, counters, last, now.Sub(lastReport))
+			lastReport = now
+		}
+	}
+}
+
+// 输出本统计区间的吞吐量和错误率
+func printInterval(w io.Writer, counters map[string]*counter, last map[string]snapshot, elapsed time.Duration) {
+	var sends, errors, latency int64
+	for _, source := range sources {
+		current := counters[source].snapshot()
+		previous := last[source]
+		sends += current.sends - previous.sends
+		errors += current.errors - previous.errors
+		latency += current.latency - previous.latency
+		last[source] = current
+	}
+	_, _ = fmt.Fprintf(w, "[%s] 发送 %d 次（%.1f/s），失败 %d 次（%.2f%%），平均耗时 %s\n",
+		time.Now().Format("15:04:05"), sends, float64(sends)/elapsed.Seconds(),
+		errors, percent(errors, sends), average(latency, sends))
+}
+
+// 输出按数据来源汇总的最终统计
+func printSummary(w io.Writer, counters map[string]*counter, elapsed time.Duration) {
+	_, _ = fmt.Fprintf(w, "\n运行 %s，按数据来源汇总：\n", elapsed.Round(time.Second))
+	_, _ = fmt.Fprintf(w, "%-8s %10s %10s %10s %8s %12s %12s\n", "source", "sends", "rate/s", "errors", "error%", "avg", "max")
+
+	names := append([]string(nil), sources...)
+	sort.Strings(names)
+	var total snapshot
+	for _, source := range names {
+		c := counters[source]
+		s := c.snapshot()
+		total.sends += s.sends
+		total.errors += s.errors
+		total.latency += s.latency
+		_, _ = fmt.Fprintf(w, "%-8s %10d %10.1f %10d %7.2f%% %12s %12s\n", source, s.sends,
+			float64(s.sends)/elapsed.Seconds(), s.errors, percent(s.errors, s.sends),
+			average(s.latency, s.sends), time.Duration(c.maxLatency.Load()).Round(time.Microsecond))
+	}
+	_, _ = fmt.Fprintf(w, "%-8s %10d %10.1f %10d %7.2f%% %12s\n", "total", total.sends,
+		float64(total.sends)/elapsed.Seconds(), total.errors, percent(total.errors, total.sends),
+		average(total.latency, total.sends))
+
+	for _, source := range names {
+		c := counters[source]
+		c.mu.Lock()
+		if c.lastError != "" {
+			_, _ = fmt.Fprintf(w, "%s 最近一次错误: %s\n", source, c.lastError)
+		}
+		c.mu.Unlock()
+	}
+}
+
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+func average(latency, count int64) time.Duration {
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(latency / count).Round(time.Microsecond)
+}
+
+// 虚拟 agent：主机名、项目和硬件规格固定，指标数据随机波动
+type agent struct {
+	hostName  string
+	project   string
+	version   float64
+	cpuCount  int
+	memTotal  uint64
+	diskTotal uint64
+	pods      []Middleware.ContainerResource
+	domains   []string
+}
+
+func newAgent(index int, projects int, version float64) *agent {
+	r := rand.New(rand.NewSource(int64(index)))
+	a := &agent{
+		hostName:  fmt.Sprintf("sim-%05d", index),
+		project:   fmt.Sprintf("sim-project-%02d", index%projects),
+		version:   version,
+		cpuCount:  []int{2, 4, 8, 16, 32}[r.Intn(5)],
+		memTotal:  uint64(4+r.Intn(60)) << 20, // 与 /proc/meminfo 一致为 KB
+		diskTotal: uint64(50+r.Intn(950)) << 30,
+	}
+	podCount, domainCount := 5+r.Intn(20), 1+r.Intn(5)
+	for i := 0; i < podCount; i++ {
+		controller := fmt.Sprintf("app-%02d", i)
+		a.pods = append(a.pods, Middleware.ContainerResource{
+			Namespace:      fmt.Sprintf("ns-%d", i%3),
+			PodName:        fmt.Sprintf("%s-%s-%05d", controller, a.hostName, r.Intn(100000)),
+			ControllerName: controller,
+			Container:      controller,
+			LimitCpu:       2,
+			LimitMemory:    2 << 30,
+			RequestCpu:     0.5,
+			RequestMemory:  512 << 20,
+		})
+	}
+	for i := 0; i < domainCount; i++ {
+		a.domains = append(a.domains, fmt.Sprintf("www%d.%s.example.com", i, a.hostName))
+	}
+	return a
+}
+
+// 按数据来源的采集周期循环发送，直到 ctx 取消
+func (a *agent) run(ctx context.Context, opts Options, source string, r *rand.Rand, c *counter) {
+	interval := Collect.Interval(source)
+
+	// 真实 agent 在周期整点发送；打散时每个虚拟 agent 在周期内随机偏移
+	var offset time.Duration
+	if opts.Spread {
+		offset = time.Duration(r.Int63n(int64(interval)))
+	}
+	next := time.Now().Truncate(interval).Add(offset)
+	url := opts.MetricsURL + "/metrics_data"
+
+	for {
+		for !next.After(time.Now()) {
+			next = next.Add(interval)
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		err := Middleware.SendData(url, a.project, a.generate(source, r), []byte(opts.Key), source)
+		c.record(time.Since(start), err)
+	}
+}
+
+// 生成与真实采集结果结构一致的合成数据
+func (a *agent) generate(source string, r *rand.Rand) interface{} {
+	switch source {
+	case "hard":
+		return []Middleware.FlatSystemInfo{a.systemInfo(r)}
+	case "heart":
+		return []Middleware.HeartSource{{IsActive: 1, Project: a.project, Hostname: a.hostName, Version: a.version}}
+	case "k8s":
+		resources := make([]Middleware.ContainerResource, len(a.pods))
+		for i, pod := range a.pods {
+			pod.UseCpu = float64(r.Intn(2000)) / 1000
+			pod.UseMemory = r.Int63n(pod.LimitMemory)
+			resources[i] = pod
+		}
+		return resources
+	case "ssl":
+		infos := make([]Middleware.DomainInfo, len(a.domains))
+		for i, domain := range a.domains {
+			daysLeft := r.Intn(365)
+			infos[i] = Middleware.DomainInfo{
+				Domain:     domain,
+				Comment:    "simulated",
+				Expiration: time.Now().AddDate(0, 0, daysLeft).Truncate(time.Second),
+				DaysLeft:   daysLeft,
+				Status:     "有效",
+				Resolve:    true,
+			}
+		}
+		return infos
+	}
+	return nil
+}
+
+func (a *agent) systemInfo(r *rand.Rand) Middleware.FlatSystemInfo {
+	memUsed := uint64(r.Int63n(int64(a.memTotal)))
+	diskUsed := uint64(r.Int63n(int64(a.diskTotal)))
+	load := r.Float64() * float64(a.cpuCount)
+	return Middleware.FlatSystemInfo{
+		CPUPercent:        float64(r.Intn(10000)) / 100,
+		DiskTotal:         a.diskTotal,
+		DiskUsed:          diskUsed,
+		DiskFree:          a.diskTotal - diskUsed,
+		DiskUsedPercent:   float64(diskUsed) * 100 / float64(a.diskTotal),
+		MemoryTotal:       a.memTotal,
+		MemoryUsed:        memUsed,
+		MemoryFree:        a.memTotal - memUsed,
+		MemoryAvailable:   a.memTotal - memUsed,
+		MemoryUsedPercent: float64(memUsed) * 100 / float64(a.memTotal),
+		CPULoad1:          load,
+		CPULoad5:          load * 0.9,
+		CPULoad15:         load * 0.8,
+		HostName:          a.hostName,
+		CPUCount:          a.cpuCount,
+		CPUModel:          "Simulated CPU",
+		OSVersion:         "Simulated Linux 1.0",
+		KernelVersion:     "6.0.0-sim",
+		Scope:             "host",
+	}
+}
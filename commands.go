@@ -0,0 +1,219 @@
+package main
+
+import (
+	"agent/Daemon"
+	"agent/Middleware"
+	"agent/Receiver"
+	"agent/Simulate"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// 子命令：agent <command> [参数]，各子命令返回进程退出码
+var commands = map[string]func(args []string) int{
+	"install":   serviceCommand(func(opts Daemon.ServiceOptions) int { return exitOnError(Daemon.Install(opts)) }),
+	"uninstall": serviceCommand(func(opts Daemon.ServiceOptions) int { return exitOnError(Daemon.Uninstall(opts)) }),
+	"start":     serviceCommand(func(opts Daemon.ServiceOptions) int { return exitOnError(Daemon.Start(opts)) }),
+	"stop":      serviceCommand(func(opts Daemon.ServiceOptions) int { return exitOnError(Daemon.Stop(opts)) }),
+	"status": serviceCommand(func(opts Daemon.ServiceOptions) int {
+		if !Daemon.Status(opts) {
+			return 3 // 与 LSB 约定一致：服务未运行
+		}
+		return 0
+	}),
+	"decode":   decodeCommand,
+	"receiver": receiverCommand,
+	"simulate": simulateCommand,
+}
+
+// 执行子命令，返回是否为已知子命令
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return false
+	}
+	os.Exit(command(args))
+	return true
+}
+
+// 服务管理子命令：agent <command> [-config config.yaml] [-lock agent.lock]
+func serviceCommand(run func(opts Daemon.ServiceOptions) int) func(args []string) int {
+	return func(args []string) int {
+		fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+		configPath := fs.String("config", "config.yaml", "配置文件路径")
+		lockPath := fs.String("lock", Daemon.DefaultLockPath(), "守护模式单实例锁文件（绝对路径）")
+		_ = fs.Parse(args[1:])
+
+		absConfig, err := filepath.Abs(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "解析配置文件路径失败: %v\n", err)
+			return 1
+		}
+		return run(Daemon.ServiceOptions{
+			Version:    Version,
+			ConfigPath: absConfig,
+			LockPath:   *lockPath,
+		})
+	}
+}
+
+// 离线解码抓包得到的请求体：agent decode [-key 密钥 | -config config.yaml] [-base64] [文件，默认 stdin]
+func decodeCommand(args []string) int {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	key := fs.String("key", "", "加密密钥，默认使用配置文件中的 encrypted")
+	configPath := fs.String("config", "config.yaml", "配置文件路径，未指定 -key 时从中读取密钥")
+	isBase64 := fs.Bool("base64", false, "输入为 base64 编码的请求体")
+	raw := fs.Bool("raw", false, "校验失败时仍打印解压后的原始 JSON")
+	_ = fs.Parse(args[1:])
+
+	secret, err := loadKey(*key, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() > 0 && fs.Arg(0) != "-" {
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "打开文件失败: %v\n", err)
+			return 1
+		}
+		defer func() { _ = file.Close() }()
+		input = file
+	}
+	body, err := io.ReadAll(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取请求体失败: %v\n", err)
+		return 1
+	}
+	if *isBase64 {
+		body, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "base64 解码失败: %v\n", err)
+			return 1
+		}
+	}
+
+	sendData, jsonData, err := Middleware.DecodePayload(body, []byte(secret))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if *raw && jsonData != nil {
+			_, _ = os.Stdout.Write(jsonData)
+			fmt.Println()
+		}
+		return 1
+	}
+
+	output, err := json.MarshalIndent(sendData, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "格式化输出失败: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(output))
+	return 0
+}
+
+// 参考接收端：agent receiver [-listen :8080] [-key 密钥 | -config config.yaml] [-dir receiver-data] [-binary 新版本 -version 版本号]
+func receiverCommand(args []string) int {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8080", "监听地址")
+	key := fs.String("key", "", "加密密钥，默认使用配置文件中的 encrypted")
+	configPath := fs.String("config", "config.yaml", "配置文件路径，未指定 -key 时从中读取密钥")
+	dataDir := fs.String("dir", "receiver-data", "数据保存目录，每个数据来源一个 JSONL 文件")
+	binary := fs.String("binary", "", "通过 /agent/agent 提供的新版本二进制")
+	version := fs.String("version", "", "通过 /version 提供的版本号")
+	_ = fs.Parse(args[1:])
+
+	secret, err := loadKey(*key, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return exitOnError(Receiver.Run(Receiver.Options{
+		Listen:  *listen,
+		Key:     secret,
+		DataDir: *dataDir,
+		Binary:  *binary,
+		Version: *version,
+	}))
+}
+
+// 服务端压测：agent simulate [-agents 100] [-projects 10] [-duration 5m] [-report 10s] [-spread] [-url 服务端] [-key 密钥 | -config config.yaml]
+func simulateCommand(args []string) int {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	agents := fs.Int("agents", 100, "虚拟 agent 数量")
+	projects := fs.Int("projects", 10, "虚拟 agent 平均分配到的项目数量")
+	duration := fs.Duration("duration", 0, "运行时长，0 表示直到 Ctrl-C")
+	report := fs.Duration("report", 10*time.Second, "输出统计的间隔")
+	spread := fs.Bool("spread", false, "在采集周期内打散各虚拟 agent 的发送时间（默认与真实 agent 一样整点对齐）")
+	metricsURL := fs.String("url", "", "服务端地址，默认使用配置文件中的 metrics_url")
+	key := fs.String("key", "", "加密密钥，默认使用配置文件中的 encrypted")
+	configPath := fs.String("config", "config.yaml", "配置文件路径，未指定 -url 或 -key 时从中读取")
+	_ = fs.Parse(args[1:])
+
+	if *metricsURL == "" {
+		config, err := Middleware.ReadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+			return 1
+		}
+		*metricsURL = config.Agent.MetricsURL
+	}
+	secret, err := loadKey(*key, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return exitOnError(Simulate.Run(ctx, Simulate.Options{
+		Agents:     *agents,
+		Projects:   *projects,
+		MetricsURL: *metricsURL,
+		Key:        secret,
+		Version:    Version,
+		Duration:   *duration,
+		Report:     *report,
+		Spread:     *spread,
+		Output:     os.Stdout,
+	}))
+}
+
+// 获取加密密钥：优先使用命令行参数，否则读取配置文件中的 encrypted
+func loadKey(key string, configPath string) (string, error) {
+	if key != "" {
+		return key, nil
+	}
+	// 只读取已有的配置文件，不在当前目录生成默认配置
+	config, err := Middleware.ReadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("加载配置文件失败: %v", err)
+	}
+	if config.Encrypted == "" {
+		return "", fmt.Errorf("未指定密钥：请使用 -key 或在配置文件中设置 encrypted")
+	}
+	return config.Encrypted, nil
+}
+
+func exitOnError(err error) int {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -1,153 +1,107 @@
 package main
 
 import (
+	"agent/Api"
 	"agent/Collect"
+	"agent/Daemon"
+	"agent/Metrics"
 	"agent/Middleware"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"os/exec"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
 
 var Version string // 版本号变量
 
-const (
-	PIDFILE     = "work.pid"
-	RESTARTFLAG = "restart.flag"
-)
-
 func main() {
 	if Version == "" {
 		Version = "1.0"
 	}
-	log.Printf("当前版本号：%s\n", Version)
+
+	// 服务管理子命令：install、uninstall、start、stop、status
+	if runCommand(os.Args[1:]) {
+		return
+	}
 
 	daemonMode := flag.Bool("d", false, "守护模式运行（自动重启+防多开）")
+	lockPath := flag.String("lock", Daemon.DefaultLockPath(), "守护模式单实例锁文件（绝对路径）")
+	showVersion := flag.Bool("version", false, "打印版本号后退出（自动更新时用于校验新版本）")
+	flag.StringVar(&Middleware.ConfigPath, "config", Middleware.ConfigPath, "配置文件路径")
+	once := flag.Bool("once", false, "执行一轮采集并等待发送完成后退出，用于排查问题")
+	sources := flag.String("source", "", "配合 -once 只执行指定的数据来源，逗号分隔，如 hard,k8s")
+	dryRun := flag.Bool("dry-run", false, "不加密也不发送，将要发送的 SendDataType JSON 逐行打印到 stdout")
 	flag.Parse()
 
-	if *daemonMode {
-		runForever()
-	} else {
-		work()
+	if *showVersion {
+		fmt.Println(Version)
+		return
 	}
-}
 
-func writePID() {
-	pid := os.Getpid()
-	file, err := os.OpenFile(PIDFILE, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		log.Fatalf("无法创建 PID 文件: %v", err)
+	if err := Middleware.SetupLogger(Middleware.LogConfig{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
-	defer func() { _ = file.Close() }()
+	slog.Info("当前版本号", "version", Version)
 
-	_, err = file.WriteString(fmt.Sprintf("%d\n", pid))
-	if err != nil {
-		log.Fatalf("无法写入 PID 文件: %v", err)
+	if *dryRun {
+		Collect.SetDryRun(os.Stdout)
 	}
-}
 
-// 清理PID文件
-func cleanPID() {
-	if err := os.Remove(PIDFILE); err != nil && !os.IsNotExist(err) {
-		log.Printf("清理 PID 文件失败: %v", err)
+	switch {
+	case *once:
+		os.Exit(runOnce(*sources))
+	case *daemonMode:
+		Daemon.RunForever(Version, *lockPath)
+	default:
+		work()
 	}
 }
 
-// 守护模式：监控工作进程，支持自动重启和更新
-func runForever() {
-	log.Println("守护模式启动")
-
-	// 检查是否已有进程运行（防止多开）
-	if isAlreadyRunning() {
-		log.Fatalf("已有实例在运行，退出")
-	}
-
-	writePID()
-	defer cleanPID()
-
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// 获取当前可执行文件路径
-	exePath, err := os.Executable()
+// 单次运行模式，返回进程退出码
+func runOnce(sources string) int {
+	config, err := Middleware.LoadConfig()
 	if err != nil {
-		log.Fatalf("获取可执行文件路径失败: %v", err)
+		slog.Error("加载配置文件失败", "path", Middleware.ConfigPath, "err", err)
+		return Daemon.ExitConfigError
 	}
-
-	for {
-		_ = os.Remove(RESTARTFLAG)
-
-		log.Println("启动工作进程...")
-		cmd := exec.Command(exePath)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Start(); err != nil {
-			log.Printf("启动失败: %v，5秒后重试", err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-
-		log.Printf("工作进程PID: %d", cmd.Process.Pid)
-
-		done := make(chan error, 1)
-		go func() { done <- cmd.Wait() }()
-
-		select {
-		case <-sigChan:
-			log.Println("收到退出信号，正在停止...")
-			if cmd.Process != nil {
-				_ = cmd.Process.Signal(syscall.SIGTERM)
-				select {
-				case <-done:
-				case <-time.After(10 * time.Second):
-					_ = cmd.Process.Kill()
-				}
-			}
-			log.Println("已停止")
-			return
-
-		case err := <-done:
-			if _, e := os.Stat(RESTARTFLAG); e == nil {
-				log.Println("检测到更新，立即重启...")
-				continue
-			}
-			if err != nil {
-				log.Printf("工作进程异常退出: %v，5秒后重启", err)
-			} else {
-				log.Println("工作进程退出，5秒后重启")
-			}
-			time.Sleep(5 * time.Second)
-		}
+	if err := Middleware.SetupLogger(config.Log); err != nil {
+		slog.Warn("日志配置无效，使用默认日志", "err", err)
 	}
-}
+	Metrics.SetHostRoot(config.Agent.HostRoot)
 
-// 检查是否已有进程运行
-func isAlreadyRunning() bool {
-	data, err := os.ReadFile(PIDFILE)
-	if err != nil {
-		return false
-	}
-	var pid int
-	if _, err := fmt.Sscanf(string(data), "%d", &pid); err != nil {
-		return false
+	var selected []string
+	if sources != "" {
+		selected = strings.Split(sources, ",")
 	}
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return false
+	if err := Collect.RunOnce(Version, config, selected); err != nil {
+		slog.Error("单次采集未全部成功", "err", err)
+		return 1
 	}
-	return process.Signal(syscall.Signal(0)) == nil
+	return 0
 }
 
 func work() {
 	// 加载配置
 	config, err := Middleware.LoadConfig()
 	if err != nil {
-		log.Fatalf("加载配置文件失败: %v", err)
+		// 以约定的退出码退出，守护进程据此区分配置错误与运行时崩溃
+		slog.Error("加载配置文件失败", "path", Middleware.ConfigPath, "err", err)
+		os.Exit(Daemon.ExitConfigError)
 	}
+	// 刚创建的默认配置或未填写上报地址和密钥时无法上报，重启也无法恢复
+	if config.Agent.MetricsURL == "" || config.Encrypted == "" {
+		slog.Error("配置文件未填写 agent.metrics_url 或 encrypted", "path", Middleware.ConfigPath)
+		os.Exit(Daemon.ExitConfigError)
+	}
+
+	if err := Middleware.SetupLogger(config.Log); err != nil {
+		slog.Warn("日志配置无效，使用默认日志", "err", err)
+	}
+	Metrics.SetHostRoot(config.Agent.HostRoot)
 
 	// 设置信号处理，支持优雅退出
 	sigChan := make(chan os.Signal, 1)
@@ -157,25 +111,83 @@ func work() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
+	// 定期写入状态文件，供 status 子命令查看
+	statusTicker := time.NewTicker(15 * time.Second)
+	defer statusTicker.Stop()
+	writeStatus()
+
+	// 本地状态与调试接口（可选）
+	if config.Api.Enable {
+		go func() {
+			if err := Api.Serve(Version, config); err != nil {
+				slog.Error("本地接口已停止", "err", err)
+			}
+		}()
+	}
+
 	// 如果启用了自动更新，启动心跳检查
 	if config.Agent.AutoUpdate {
-		log.Printf("已开启自动更新")
+		slog.Info("已开启自动更新")
 		go Middleware.AutoChecks(Version)
 	}
 
-	log.Printf("Agent 已启动，PID: %d", os.Getpid())
+	slog.Info("Agent 已启动", "pid", os.Getpid(), "version", Version)
+
+	// systemd 看门狗：心跳在主循环的 ticker 分支中发送，主循环卡住时 systemd 会重启进程
+	watchdogInterval := Daemon.WatchdogInterval()
+	var lastWatchdog time.Time
+	ready := false
 
 	// 主循环处理数据收集和发送
 	for {
 		select {
 		case sig := <-sigChan:
-			log.Printf("收到信号 %v，正在优雅退出...", sig)
-			cleanPID()
-			log.Println("Agent 已停止")
+			slog.Info("收到信号，正在优雅退出", "signal", sig.String())
+			notify("STOPPING=1")
+			slog.Info("Agent 已停止")
+			return
+		case <-Middleware.UpdateStaged:
+			// 新版本已下载校验完毕，退出后由守护进程替换并重启
+			slog.Info("新版本已就绪，退出以便守护进程完成更新")
+			notify("STOPPING=1")
 			return
 		case <-ticker.C:
 			// 收集并发送数据
 			Collect.CollectAndSendMetrics(Version, config)
+
+			// 配置已加载且调度已开始运行，通知 systemd 启动完成
+			if !ready {
+				notify("READY=1\nSTATUS=采集已启动")
+				ready = true
+			}
+			if watchdogInterval > 0 && time.Since(lastWatchdog) >= watchdogInterval {
+				notify("WATCHDOG=1")
+				lastWatchdog = time.Now()
+			}
+		case <-statusTicker.C:
+			writeStatus()
+			notify("STATUS=最近发送: " + Middleware.SummarizeSends())
+		}
+	}
+}
+
+// 向 systemd 发送通知，失败只记录日志
+func notify(state string) {
+	if err := Daemon.Notify(state); err != nil {
+		slog.Warn("发送 systemd 通知失败", "err", err)
+	}
+}
+
+// 写入状态文件，失败只记录一次，避免只读目录下刷屏
+var statusWriteFailed bool
+
+func writeStatus() {
+	if err := Middleware.WriteStatusFile(Version); err != nil {
+		if !statusWriteFailed {
+			slog.Warn("写入状态文件失败", "err", err)
 		}
+		statusWriteFailed = true
+		return
 	}
+	statusWriteFailed = false
 }